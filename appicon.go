@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// dbusGetNameOwner is the method of the bus returning the unique name owning a bus name.
+const dbusGetNameOwner = "org.freedesktop.DBus.GetNameOwner"
+
+// WithDefaultAppIcon sets the AppIcon used for notifications sent without one.
+// Notification.AppIcon always takes precedence, the default is only used when it is empty.
+// See also FindAppIcon.
+func WithDefaultAppIcon(iconName string) option {
+	return func(n *notifier) {
+		n.appIconDefault = iconName
+	}
+}
+
+// WithIconAutoResolve sets AppIcon of notifications sent without one to the icon of the application
+// named by AppName, for applications with a well-known bus name as AppName, e.g. "org.gnome.Nautilus".
+//
+// If a bus name AppName is owned, i.e. the application is running, its icon is looked up with FindAppIcon,
+// as applications with a bus name name their desktop entry after it. The icon is cached per AppName,
+// also when none is found. If the name is not owned, or AppName is not a bus name, AppIcon is left empty,
+// and the default of WithDefaultAppIcon is used if set.
+//
+// Looking up the owner of AppName adds a round trip to the bus to the first notification of each AppName,
+// and to every notification of an AppName that is not running.
+func WithIconAutoResolve() option {
+	return func(n *notifier) {
+		n.iconCache = &appIconCache{icons: map[string]string{}}
+	}
+}
+
+// appIconCache holds the icons resolved by WithIconAutoResolve, keyed by AppName.
+type appIconCache struct {
+	mu    sync.Mutex
+	icons map[string]string
+}
+
+// resolveAppIcon returns the icon of the running application with the bus name appName, or "".
+func (n *notifier) resolveAppIcon(ctx context.Context, appName string) string {
+	if !isWellKnownBusName(appName) {
+		return ""
+	}
+	c := n.iconCache
+	c.mu.Lock()
+	icon, ok := c.icons[appName]
+	c.mu.Unlock()
+	if ok {
+		return icon
+	}
+
+	var owner string
+	if err := n.conn.BusObject().CallWithContext(ctx, dbusGetNameOwner, 0, appName).Store(&owner); err != nil {
+		// not running, try again next time
+		return ""
+	}
+	icon = FindAppIcon(appName)
+	c.mu.Lock()
+	c.icons[appName] = icon
+	c.mu.Unlock()
+	return icon
+}
+
+// isWellKnownBusName returns true if name is a valid well-known bus name, such as org.example.App.
+func isWellKnownBusName(name string) bool {
+	if len(name) > 255 || name == "" || name[0] == ':' {
+		return false
+	}
+	elements := strings.Split(name, ".")
+	if len(elements) < 2 {
+		return false
+	}
+	for _, e := range elements {
+		if e == "" || (e[0] >= '0' && e[0] <= '9') {
+			return false
+		}
+		for _, c := range e {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FindAppIcon returns the icon of the application with appID, e.g. "org.gnome.Nautilus",
+// from the Icon key of its desktop entry. Returns "" if no desktop entry or icon is found.
+//
+// The desktop entry <appID>.desktop is looked up in the applications directory of
+// $XDG_DATA_HOME (default ~/.local/share), followed by those of $XDG_DATA_DIRS
+// (default /usr/local/share:/usr/share).
+func FindAppIcon(appID string) string {
+	for _, dir := range xdgDataDirs() {
+		if icon, ok := readDesktopEntryIcon(filepath.Join(dir, "applications", appID+".desktop")); ok {
+			return icon
+		}
+	}
+	return ""
+}
+
+// xdgDataDirs returns the base directories for data files, in order of preference.
+func xdgDataDirs() []string {
+	var dirs []string
+	if home := os.Getenv("XDG_DATA_HOME"); home != "" {
+		dirs = append(dirs, home)
+	} else if userHome, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(userHome, ".local", "share"))
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range strings.Split(dataDirs, ":") {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// readDesktopEntryIcon reads the Icon key of the [Desktop Entry] group of the desktop entry at path.
+func readDesktopEntryIcon(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	inDesktopEntry := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inDesktopEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if !inDesktopEntry {
+			continue
+		}
+		key, value, ok := cutKeyValue(line)
+		if ok && key == "Icon" && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// cutKeyValue splits a desktop entry line on the form key=value.
+func cutKeyValue(line string) (string, string, bool) {
+	i := strings.IndexByte(line, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAppIcon(t *testing.T) {
+	home, err := ioutil.TempDir("", "notify-data-home")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	system, err := ioutil.TempDir("", "notify-data-dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(system)
+
+	for _, env := range []string{"XDG_DATA_HOME", "XDG_DATA_DIRS"} {
+		old, ok := os.LookupEnv(env)
+		defer func(env string) {
+			if ok {
+				_ = os.Setenv(env, old)
+			} else {
+				_ = os.Unsetenv(env)
+			}
+		}(env)
+	}
+	_ = os.Setenv("XDG_DATA_HOME", home)
+	_ = os.Setenv("XDG_DATA_DIRS", system)
+
+	writeEntry := func(dir, appID, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "applications"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "applications", appID+".desktop"), []byte(content), 0644))
+	}
+	writeEntry(system, "org.example.App", "[Desktop Entry]\nName=App\nIcon=system-icon\n")
+	writeEntry(system, "org.example.Other", "[Desktop Action new]\nIcon=action-icon\n[Desktop Entry]\nIcon = other-icon\n")
+	require.Equal(t, "system-icon", FindAppIcon("org.example.App"))
+	require.Equal(t, "other-icon", FindAppIcon("org.example.Other"))
+
+	// the user's entry takes precedence
+	writeEntry(home, "org.example.App", "[Desktop Entry]\nIcon=user-icon\n")
+	require.Equal(t, "user-icon", FindAppIcon("org.example.App"))
+
+	require.Equal(t, "", FindAppIcon("org.example.Missing"))
+}
+
+func TestIsWellKnownBusName(t *testing.T) {
+	for _, name := range []string{"org.example.App", "org.example.my-app", "a._b"} {
+		require.True(t, isWellKnownBusName(name), name)
+	}
+	for _, name := range []string{"", "app", "My App", ":1.42", "org..App", "org.1app", "org.example.App!"} {
+		require.False(t, isWellKnownBusName(name), name)
+	}
+}
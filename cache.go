@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache holds a value fetched from the server for up to ttl, or until flushed when ttl is 0.
+// Failed fetches are not cached.
+type ttlCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	value     interface{}
+	fetchedAt time.Time
+	fetched   bool
+}
+
+// get returns the cached value, calling fetch if none is cached or it is stale.
+func (c *ttlCache) get(fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetched && (c.ttl == 0 || time.Since(c.fetchedAt) < c.ttl) {
+		return c.value, nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.value, c.fetchedAt, c.fetched = value, time.Now(), true
+	return value, nil
+}
+
+// flush forgets the cached value.
+func (c *ttlCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value, c.fetched = nil, false
+}
+
+// WithCacheServerInformation caches the result of GetServerInformation for ttl,
+// after which it is fetched again on next use. A ttl of 0 caches it until FlushCache is called.
+func WithCacheServerInformation(ttl time.Duration) option {
+	return func(n *notifier) {
+		n.infoCache = &ttlCache{ttl: ttl}
+	}
+}
+
+// FlushCache forgets the cached capabilities and server information,
+// so they are fetched from the server again on next use.
+func (n *notifier) FlushCache() {
+	n.capsCache.flush()
+	if n.infoCache != nil {
+		n.infoCache.flush()
+	}
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLCacheConcurrentGet(t *testing.T) {
+	c := &ttlCache{}
+	var fetches int32
+	fetch := func() (interface{}, error) {
+		atomic.AddInt32(&fetches, 1)
+		return "value", nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.get(fetch)
+			require.NoError(t, err)
+			require.Equal(t, "value", v)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	c.flush()
+	_, err := c.get(fetch)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestTTLCacheExpiry(t *testing.T) {
+	c := &ttlCache{ttl: time.Minute}
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	v, err := c.get(fetch)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	c.fetchedAt = time.Now().Add(-2 * time.Minute)
+	v, err = c.get(fetch)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+}
+
+func TestTTLCacheErrorNotCached(t *testing.T) {
+	c := &ttlCache{}
+	_, err := c.get(func() (interface{}, error) { return nil, errors.New("no server") })
+	require.Error(t, err)
+
+	v, err := c.get(func() (interface{}, error) { return "value", nil })
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+}
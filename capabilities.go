@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// Capabilities defined by the spec, as returned from GetCapabilities.
+// See: https://specifications.freedesktop.org/notification-spec/latest/ar01s09.html
+const (
+	// CapabilityActionIcons: supports using icons instead of text for displaying actions.
+	CapabilityActionIcons = "action-icons"
+	// CapabilityActions: the server will provide the specified actions to the user.
+	CapabilityActions = "actions"
+	// CapabilityBody: supports body text.
+	CapabilityBody = "body"
+	// CapabilityBodyHyperlinks: the server supports hyperlinks in the notifications.
+	CapabilityBodyHyperlinks = "body-hyperlinks"
+	// CapabilityBodyImages: the server supports images in the notifications.
+	CapabilityBodyImages = "body-images"
+	// CapabilityBodyMarkup: supports markup in the body text.
+	CapabilityBodyMarkup = "body-markup"
+	// CapabilityIconMulti: the server will render an animation of all the frames in a given image array.
+	CapabilityIconMulti = "icon-multi"
+	// CapabilityIconStatic: supports display of exactly 1 frame of any given image array.
+	CapabilityIconStatic = "icon-static"
+	// CapabilityPersistence: the server supports persistence of notifications.
+	CapabilityPersistence = "persistence"
+	// CapabilitySound: the server supports sounds on notifications.
+	CapabilitySound = "sound"
+)
+
+// Capabilities is the list of optional capabilities implemented by a notification server.
+type Capabilities []string
+
+// Has returns true if capability is in c.
+func (c Capabilities) Has(capability string) bool {
+	for _, v := range c {
+		if v == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerCapabilities bundles the capabilities and the information of a notification server.
+type ServerCapabilities struct {
+	Capabilities      Capabilities
+	ServerInformation ServerInformation
+}
+
+// NewServerCapabilities fetches both capabilities and server information from the notification server.
+// This takes two round trips to the server.
+func NewServerCapabilities(conn *dbus.Conn) (ServerCapabilities, error) {
+	return fetchServerCapabilities(
+		func() ([]string, error) { return GetCapabilities(conn) },
+		func() (ServerInformation, error) { return GetServerInformation(conn) },
+	)
+}
+
+func fetchServerCapabilities(
+	getCapabilities func() ([]string, error),
+	getServerInformation func() (ServerInformation, error),
+) (ServerCapabilities, error) {
+	caps, err := getCapabilities()
+	if err != nil {
+		return ServerCapabilities{}, err
+	}
+	info, err := getServerInformation()
+	if err != nil {
+		return ServerCapabilities{}, err
+	}
+	return ServerCapabilities{
+		Capabilities:      caps,
+		ServerInformation: info,
+	}, nil
+}
+
+// SupportsMarkup returns true if the server supports markup in the body text.
+func (sc ServerCapabilities) SupportsMarkup() bool {
+	return sc.Capabilities.Has(CapabilityBodyMarkup)
+}
+
+// SupportsActions returns true if the server will show actions to the user.
+func (sc ServerCapabilities) SupportsActions() bool {
+	return sc.Capabilities.Has(CapabilityActions)
+}
+
+// SupportsImages returns true if the server can display images in notifications.
+func (sc ServerCapabilities) SupportsImages() bool {
+	return sc.Capabilities.Has(CapabilityIconStatic) ||
+		sc.Capabilities.Has(CapabilityIconMulti) ||
+		sc.Capabilities.Has(CapabilityBodyImages)
+}
+
+// SupportsSound returns true if the server supports sounds on notifications.
+func (sc ServerCapabilities) SupportsSound() bool {
+	return sc.Capabilities.Has(CapabilitySound)
+}
+
+// ServerCapabilities fetches both capabilities and server information from the notification server.
+func (n *notifier) ServerCapabilities() (ServerCapabilities, error) {
+	return fetchServerCapabilities(n.GetCapabilities, n.GetServerInformation)
+}
+
+// cachedCapabilities returns the capabilities of the server, fetching them on first use.
+// Failed fetches are not cached. A server that does not implement GetCapabilities
+// is cached as having no optional capabilities.
+func (n *notifier) cachedCapabilities() (Capabilities, error) {
+	caps, err := n.capsCache.get(func() (interface{}, error) {
+		caps, err := n.GetCapabilities()
+		if IsCapabilitiesUnsupported(err) {
+			n.log.Printf("%v, assuming no optional capabilities", err)
+			return []string{}, nil
+		}
+		return caps, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Capabilities(caps.([]string)), nil
+}
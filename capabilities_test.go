@@ -0,0 +1,19 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerCapabilitiesSupports(t *testing.T) {
+	sc := ServerCapabilities{
+		Capabilities: Capabilities{CapabilityBody, CapabilityActions, CapabilityIconStatic},
+	}
+	require.True(t, sc.SupportsActions())
+	require.True(t, sc.SupportsImages())
+	require.False(t, sc.SupportsMarkup())
+	require.False(t, sc.SupportsSound())
+
+	require.False(t, ServerCapabilities{}.SupportsActions())
+}
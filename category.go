@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Category is the type of notification, sent with the "category" hint.
+// See: https://specifications.freedesktop.org/notification-spec/latest/ar01s06.html
+type Category string
+
+// Categories defined by the spec.
+const (
+	// CategoryDevice: a generic device-related notification that doesn't fit into any other category.
+	CategoryDevice Category = "device"
+	// CategoryDeviceAdded: a device, such as a USB device, was added to the system.
+	CategoryDeviceAdded Category = "device.added"
+	// CategoryDeviceError: a device had some kind of error.
+	CategoryDeviceError Category = "device.error"
+	// CategoryDeviceRemoved: a device, such as a USB device, was removed from the system.
+	CategoryDeviceRemoved Category = "device.removed"
+	// CategoryEmail: a generic e-mail-related notification that doesn't fit into any other category.
+	CategoryEmail Category = "email"
+	// CategoryEmailArrived: a new e-mail notification.
+	CategoryEmailArrived Category = "email.arrived"
+	// CategoryEmailBounced: a notification stating that an e-mail has bounced.
+	CategoryEmailBounced Category = "email.bounced"
+	// CategoryIM: a generic instant message-related notification that doesn't fit into any other category.
+	CategoryIM Category = "im"
+	// CategoryIMError: an instant message error notification.
+	CategoryIMError Category = "im.error"
+	// CategoryIMReceived: a received instant message notification.
+	CategoryIMReceived Category = "im.received"
+	// CategoryNetwork: a generic network notification that doesn't fit into any other category.
+	CategoryNetwork Category = "network"
+	// CategoryNetworkConnected: a network connection notification, such as successful sign-on to a network service.
+	CategoryNetworkConnected Category = "network.connected"
+	// CategoryNetworkDisconnected: a network disconnected notification.
+	CategoryNetworkDisconnected Category = "network.disconnected"
+	// CategoryNetworkError: a network-related or connection-related error.
+	CategoryNetworkError Category = "network.error"
+	// CategoryPresence: a generic presence change notification that doesn't fit into any other category.
+	CategoryPresence Category = "presence"
+	// CategoryPresenceOffline: an offline presence change notification.
+	CategoryPresenceOffline Category = "presence.offline"
+	// CategoryPresenceOnline: an online presence change notification.
+	CategoryPresenceOnline Category = "presence.online"
+	// CategoryTransfer: a generic file transfer or download notification that doesn't fit into any other category.
+	CategoryTransfer Category = "transfer"
+	// CategoryTransferComplete: a file transfer or download complete notification.
+	CategoryTransferComplete Category = "transfer.complete"
+	// CategoryTransferError: a file transfer or download error.
+	CategoryTransferError Category = "transfer.error"
+)
+
+var knownCategories = map[Category]bool{
+	CategoryDevice:              true,
+	CategoryDeviceAdded:         true,
+	CategoryDeviceError:         true,
+	CategoryDeviceRemoved:       true,
+	CategoryEmail:               true,
+	CategoryEmailArrived:        true,
+	CategoryEmailBounced:        true,
+	CategoryIM:                  true,
+	CategoryIMError:             true,
+	CategoryIMReceived:          true,
+	CategoryNetwork:             true,
+	CategoryNetworkConnected:    true,
+	CategoryNetworkDisconnected: true,
+	CategoryNetworkError:        true,
+	CategoryPresence:            true,
+	CategoryPresenceOffline:     true,
+	CategoryPresenceOnline:      true,
+	CategoryTransfer:            true,
+	CategoryTransferComplete:    true,
+	CategoryTransferError:       true,
+}
+
+// IsValid returns true if c is one of the categories defined by the spec.
+func (c Category) IsValid() bool {
+	return knownCategories[c]
+}
+
+// Parent returns the category c is a subcategory of, e.g. CategoryEmail for CategoryEmailArrived.
+// Returns false if c has no parent.
+func (c Category) Parent() (Category, bool) {
+	i := strings.LastIndexByte(string(c), '.')
+	if i < 0 {
+		return "", false
+	}
+	return c[:i], true
+}
+
+// ParseCategory returns s as a Category, or an error if it is not defined by the spec.
+func ParseCategory(s string) (Category, error) {
+	c := Category(s)
+	if !c.IsValid() {
+		return "", fmt.Errorf("unknown notification category: %q", s)
+	}
+	return c, nil
+}
+
+// HintFromCategory sets the "category" hint to c.
+func HintFromCategory(c Category) Hint {
+	return Hint{
+		ID:      "category",
+		Variant: dbus.MakeVariant(string(c)),
+	}
+}
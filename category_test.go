@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategory(t *testing.T) {
+	require.True(t, CategoryEmailArrived.IsValid())
+	require.False(t, Category("email.arived").IsValid())
+
+	parent, ok := CategoryEmailArrived.Parent()
+	require.True(t, ok)
+	require.Equal(t, CategoryEmail, parent)
+	_, ok = CategoryEmail.Parent()
+	require.False(t, ok)
+
+	// every subcategory has a valid parent
+	for c := range knownCategories {
+		if parent, ok := c.Parent(); ok {
+			require.True(t, parent.IsValid(), c)
+		}
+	}
+
+	c, err := ParseCategory("transfer.complete")
+	require.NoError(t, err)
+	require.Equal(t, CategoryTransferComplete, c)
+	_, err = ParseCategory("transfer.done")
+	require.Error(t, err)
+
+	hint := HintFromCategory(CategoryIMReceived)
+	require.Equal(t, "category", hint.ID)
+	require.Equal(t, "im.received", hint.Variant.Value())
+}
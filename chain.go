@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Chain sends notes one at a time, waiting for each to be resolved by an ActionInvoked
+// or NotificationClosed signal before sending the next, as in a wizard.
+//
+// The returned events have the same length as notes, in the same order.
+// If sending fails or ctx is done, the remaining notes are not sent, and the events
+// received so far are returned together with the error. Events of notes not resolved are zero.
+//
+// With the Notifier of New, no signal is missed. With other Notifiers, listening starts once a note was sent,
+// see SignalWaiter.Add.
+func Chain(ctx context.Context, notifier Notifier, notes []Notification) ([]NotificationEvent, error) {
+	events := make([]NotificationEvent, len(notes))
+	for i, note := range notes {
+		if err := ctx.Err(); err != nil {
+			return events, err
+		}
+		_, wait, err := sendAndListen(notifier, note)
+		if err != nil {
+			return events, fmt.Errorf("error sending notification %d: %w", i, err)
+		}
+		e, err := wait(ctx)
+		if err != nil {
+			return events, err
+		}
+		events[i] = e
+	}
+	return events, nil
+}
@@ -0,0 +1,20 @@
+package notify
+
+// Clone creates a new Notifier on the same connection as n, configured with the options
+// of n, overridden by opts.
+//
+// The clone has its own signal delivery loop, handlers, stats and history,
+// and receives the same signals as n. Each must be closed on its own:
+// closing one does not affect the other, except when n owns its connection,
+// as with WithSessionBusPrivate, in which case closing n also closes the connection of the clone.
+func (n *notifier) Clone(opts ...option) (Notifier, error) {
+	all := make([]option, 0, len(n.opts)+len(opts))
+	all = append(all, n.opts...)
+	all = append(all, opts...)
+
+	clone := newNotifier(all...)
+	if err := clone.start(n.conn); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
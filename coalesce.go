@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CoalescingNotifier is a Notifier that limits how often notifications of the same key are sent,
+// for senders updating a notification faster than the server can show it, e.g. live progress.
+// It wraps a base Notifier, which is left open when the CoalescingNotifier is closed.
+//
+// Only SendNotification coalesces notifications, the other methods go straight to the base Notifier.
+type CoalescingNotifier struct {
+	Notifier
+
+	window time.Duration
+	keyFn  func(Notification) string
+
+	mu     sync.Mutex
+	keys   map[string]*coalescedKey
+	closed bool
+}
+
+// coalescedKey is the notification currently shown for a key, and the update waiting for the window to end.
+type coalescedKey struct {
+	// sent is closed once the first notification of the key was sent, and id and err are set
+	sent    chan struct{}
+	id      uint32
+	err     error
+	pending *Notification
+	timer   *time.Timer
+}
+
+// NewCoalescingNotifier creates a CoalescingNotifier sending through base.
+//
+// keyFn returns the key of a notification. Notifications with an empty key are sent immediately.
+// Of the notifications with the same key, at most one is sent per window.
+func NewCoalescingNotifier(base Notifier, window time.Duration, keyFn func(Notification) string) *CoalescingNotifier {
+	return &CoalescingNotifier{
+		Notifier: base,
+		window:   window,
+		keyFn:    keyFn,
+		keys:     map[string]*coalescedKey{},
+	}
+}
+
+// Unwrap returns the base Notifier of c.
+func (c *CoalescingNotifier) Unwrap() Notifier {
+	return c.Notifier
+}
+
+// SendNotification sends n immediately if no notification with the same key was sent in the current window,
+// starting a new window. Otherwise n is held until the window ends, replacing any notification held before,
+// and the ID of the shown notification is returned. When the window ends, the held notification replaces
+// the shown notification using ReplacesID, and a new window starts.
+//
+// Held notifications are sent in the background: errors sending them are not returned, but are
+// counted by the Stats of the base Notifier.
+func (c *CoalescingNotifier) SendNotification(n Notification) (uint32, error) {
+	key := c.keyFn(n)
+	if key == "" {
+		return c.Notifier.SendNotification(n)
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return c.Notifier.SendNotification(n)
+	}
+	if k, ok := c.keys[key]; ok {
+		k.pending = &n
+		c.mu.Unlock()
+		// the ID is not known until the first notification of the key was sent
+		<-k.sent
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return k.id, k.err
+	}
+	k := &coalescedKey{sent: make(chan struct{})}
+	c.keys[key] = k
+	c.mu.Unlock()
+
+	id, err := c.Notifier.SendNotification(n)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k.id, k.err = id, err
+	close(k.sent)
+	if c.keys[key] != k {
+		// closed in the meantime
+		return id, err
+	}
+	if err != nil {
+		delete(c.keys, key)
+		return id, err
+	}
+	c.startWindow(key, k)
+	return id, nil
+}
+
+// startWindow sends the notification held for key when the window ends. Caller must hold c.mu.
+// The lock is not held while sending, so a slow server does not hold up other keys.
+func (c *CoalescingNotifier) startWindow(key string, k *coalescedKey) {
+	k.timer = time.AfterFunc(c.window, func() {
+		c.mu.Lock()
+		if c.keys[key] != k {
+			// closed in the meantime
+			c.mu.Unlock()
+			return
+		}
+		if k.pending == nil {
+			delete(c.keys, key)
+			c.mu.Unlock()
+			return
+		}
+		note := *k.pending
+		note.ReplacesID = k.id
+		k.pending = nil
+		c.mu.Unlock()
+
+		id, err := c.Notifier.SendNotification(note)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err == nil {
+			k.id = id
+		}
+		if c.keys[key] == k {
+			c.startWindow(key, k)
+		}
+	})
+}
+
+// Close sends all held notifications immediately, and stops coalescing. The base Notifier is left open.
+// Returns the first error sending held notifications. It is safe to be called multiple times.
+func (c *CoalescingNotifier) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	var held []*coalescedKey
+	var notes []Notification
+	for key, k := range c.keys {
+		if k.timer != nil {
+			k.timer.Stop()
+		}
+		if k.pending != nil {
+			held = append(held, k)
+			notes = append(notes, *k.pending)
+		}
+		delete(c.keys, key)
+	}
+	c.mu.Unlock()
+
+	var err error
+	for i, k := range held {
+		<-k.sent
+		c.mu.Lock()
+		notes[i].ReplacesID = k.id
+		c.mu.Unlock()
+		if _, sendErr := c.Notifier.SendNotification(notes[i]); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+// Shutdown closes c like Close. The base Notifier is left open.
+func (c *CoalescingNotifier) Shutdown(ctx context.Context) error {
+	return c.Close()
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingNotifier blocks sending notifications with the summary "block" until release is closed.
+type blockingNotifier struct {
+	Notifier
+
+	release chan struct{}
+	mu      sync.Mutex
+	lastID  uint32
+}
+
+func (b *blockingNotifier) SendNotification(n Notification) (uint32, error) {
+	if n.Summary == "block" {
+		<-b.release
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n.ReplacesID != 0 {
+		return n.ReplacesID, nil
+	}
+	b.lastID++
+	return b.lastID, nil
+}
+
+func TestCoalescingNotifierDoesNotBlockOtherKeys(t *testing.T) {
+	base := &blockingNotifier{release: make(chan struct{})}
+	c := NewCoalescingNotifier(base, time.Millisecond, func(n Notification) string { return n.AppName })
+
+	idA, err := c.SendNotification(Notification{AppName: "a", Summary: "first"})
+	require.NoError(t, err)
+	_, err = c.SendNotification(Notification{AppName: "a", Summary: "block"})
+	require.NoError(t, err)
+
+	// the held notification of a is stuck sending once its window ends
+	time.Sleep(10 * time.Millisecond)
+	done := make(chan uint32)
+	go func() {
+		idB, _ := c.SendNotification(Notification{AppName: "b", Summary: "other key"})
+		done <- idB
+	}()
+	select {
+	case idB := <-done:
+		require.NotZero(t, idB)
+		require.NotEqual(t, idA, idB)
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending another key was blocked by a slow send")
+	}
+
+	close(base.release)
+	require.NoError(t, c.Close())
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// DiscoveredService is a notification service found on the bus by DescribeNotificationServices.
+type DiscoveredService struct {
+	// Name is the well-known bus name owned by the service, e.g. org.freedesktop.Notifications
+	Name string
+	// Info is the reply to GetServerInformation, empty if Err is set
+	Info ServerInformation
+	// Err is the error calling GetServerInformation on the service, if any
+	Err error
+}
+
+// DiscoverNotificationServices lists the names on the bus of conn that look like notification services:
+// DefaultDestination, and any other well-known name ending in ".Notifications", e.g. org.kde.Notifications.
+// The names are sorted. Only the name is matched, so services found are not guaranteed to implement the notification API.
+//
+// Meant for debugging setups with more than one notification server, see WithDestination for using them.
+func DiscoverNotificationServices(conn *dbus.Conn) ([]string, error) {
+	names, err := listBusNames(conn)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, name := range names {
+		if name == DefaultDestination || (isWellKnownBusName(name) && strings.HasSuffix(name, ".Notifications")) {
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// listBusNames returns the names currently owned on the bus of conn, both unique and well-known.
+func listBusNames(conn *dbus.Conn) ([]string, error) {
+	var names []string
+	err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names)
+	if err != nil {
+		return nil, fmt.Errorf("error listing bus names: %w", err)
+	}
+	return names, nil
+}
+
+// DescribeNotificationServices is like DiscoverNotificationServices, and also calls GetServerInformation on each service found.
+// A service is assumed to implement an interface named like the service, at the matching object path,
+// e.g. org.kde.Notifications at /org/kde/Notifications.
+//
+// Services failing the call are still returned, with Err set.
+func DescribeNotificationServices(ctx context.Context, conn *dbus.Conn) ([]DiscoveredService, error) {
+	names, err := DiscoverNotificationServices(conn)
+	if err != nil {
+		return nil, err
+	}
+	services := make([]DiscoveredService, 0, len(names))
+	for _, name := range names {
+		service := DiscoveredService{Name: name}
+		service.Info, service.Err = getServerInformation(ctx, conn, serviceEndpoint(name))
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// serviceEndpoint returns the endpoint of a notification service named name,
+// assuming the interface has the same name, and the object path matches it.
+func serviceEndpoint(name string) endpoint {
+	return endpoint{
+		dest:  name,
+		iface: name,
+		path:  dbus.ObjectPath("/" + strings.Replace(name, ".", "/", -1)),
+	}
+}
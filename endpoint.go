@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// interfaceNameRegexp matches a syntactically valid DBus interface name:
+// two or more dot separated elements, none starting with a digit.
+var interfaceNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+
+// busNameRegexp matches a syntactically valid DBus bus name, either a well-known name
+// or a unique connection name starting with ':'.
+var busNameRegexp = regexp.MustCompile(`^([A-Za-z_-][A-Za-z0-9_-]*(\.[A-Za-z_-][A-Za-z0-9_-]*)+|:[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+)$`)
+
+// DefaultDestination is the well-known bus name notification servers own.
+const DefaultDestination = "org.freedesktop.Notifications"
+
+// endpoint identifies the DBus destination, interface and object path implementing the notification API.
+type endpoint struct {
+	dest  string
+	iface string
+	path  dbus.ObjectPath
+}
+
+var defaultEndpoint = endpoint{
+	dest:  DefaultDestination,
+	iface: dbusNotificationsInterface,
+	path:  dbusObjectPath,
+}
+
+// object returns the bus object to make calls on.
+func (e endpoint) object(conn *dbus.Conn) dbus.BusObject {
+	return conn.Object(e.dest, e.path)
+}
+
+// member returns the fully qualified name of a method or signal of the interface, e.g. org.freedesktop.Notifications.Notify
+func (e endpoint) member(name string) string {
+	return e.iface + "." + name
+}
+
+func (e endpoint) validate() error {
+	if len(e.dest) > 255 || !busNameRegexp.MatchString(e.dest) {
+		return fmt.Errorf("invalid dbus destination: %q", e.dest)
+	}
+	if len(e.iface) > 255 || !interfaceNameRegexp.MatchString(e.iface) {
+		return fmt.Errorf("invalid dbus interface name: %q", e.iface)
+	}
+	if !e.path.IsValid() {
+		return fmt.Errorf("invalid dbus object path: %q", e.path)
+	}
+	return nil
+}
+
+// WithDestination overrides the bus name calls are sent to.
+// Defaults to DefaultDestination.
+// Useful for servers that own a different well-known name, or for test daemons running under a custom name.
+func WithDestination(dest string) option {
+	return func(n *notifier) {
+		n.endpoint.dest = dest
+	}
+}
+
+// WithDBusInterface overrides the DBus interface name used for calls and signals.
+// Defaults to org.freedesktop.Notifications.
+// Useful for talking to notification bridges that do not use the standard interface.
+func WithDBusInterface(iface string) option {
+	return func(n *notifier) {
+		n.endpoint.iface = iface
+	}
+}
+
+// WithDBusObjectPath overrides the DBus object path implementing the notification interface.
+// Defaults to /org/freedesktop/Notifications.
+func WithDBusObjectPath(path dbus.ObjectPath) option {
+	return func(n *notifier) {
+		n.endpoint.path = path
+	}
+}
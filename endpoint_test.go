@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointValidate(t *testing.T) {
+	require.NoError(t, defaultEndpoint.validate())
+	require.NoError(t, endpoint{dest: "org.kde.Notifications", iface: "org.kde.Notifications", path: "/org/kde/Notifications"}.validate())
+
+	require.Error(t, endpoint{dest: DefaultDestination, iface: "", path: dbusObjectPath}.validate())
+	require.Error(t, endpoint{dest: DefaultDestination, iface: "Notifications", path: dbusObjectPath}.validate())
+	require.Error(t, endpoint{dest: DefaultDestination, iface: "org.1freedesktop", path: dbusObjectPath}.validate())
+	require.Error(t, endpoint{dest: DefaultDestination, iface: dbusNotificationsInterface, path: "org/freedesktop"}.validate())
+
+	_, err := New(nil, WithDBusInterface("not valid"))
+	require.Error(t, err)
+
+	for _, dest := range []string{"org.example.Notify-Bridge", ":1.42", "_x.y"} {
+		require.NoError(t, endpoint{dest: dest, iface: dbusNotificationsInterface, path: dbusObjectPath}.validate(), dest)
+	}
+	for _, dest := range []string{"", "Notifications", "org.1example", "org..example", ":1", "org.example."} {
+		require.Error(t, endpoint{dest: dest, iface: dbusNotificationsInterface, path: dbusObjectPath}.validate(), dest)
+	}
+	_, err = New(nil, WithDestination("not valid"))
+	require.Error(t, err)
+
+	e := endpoint{iface: "org.example.Bridge", path: "/org/example/Bridge"}
+	require.Equal(t, "org.example.Bridge.Notify", e.member(methodNotify))
+}
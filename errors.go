@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// dbusErrorsInvalidID are the DBus error names servers reply with when a notification ID is unknown.
+var dbusErrorsInvalidID = map[string]bool{
+	"org.freedesktop.Notifications.InvalidId":       true,
+	"org.freedesktop.Notifications.Error.InvalidId": true,
+}
+
+// ErrInvalidNotificationID is returned when operating on notification ID 0.
+// Servers never hand out 0 as an ID, so it can only refer to a notification that was never sent.
+var ErrInvalidNotificationID = errors.New("notify: invalid notification id 0")
+
+// ErrNotifierClosed is returned when sending or closing notifications through a Notifier
+// after Close or Shutdown has been called.
+var ErrNotifierClosed = errors.New("notify: notifier is closed")
+
+// ErrIDNotReserved is returned by SendWithID for IDs not reserved with ReserveID, or already sent.
+var ErrIDNotReserved = errors.New("notify: notification id is not reserved")
+
+// ErrDialTimeout is returned by NewSessionBusNotifier when connecting to the session bus
+// takes longer than the timeout of WithDialTimeout.
+var ErrDialTimeout = errors.New("notify: timed out connecting to session bus")
+
+// ErrGetCapabilitiesNotSupported is returned by GetCapabilities when the server does not implement the call.
+// Notifier itself treats such a server as supporting none of the optional capabilities.
+// See IsCapabilitiesUnsupported.
+var ErrGetCapabilitiesNotSupported = errors.New("notify: server does not support GetCapabilities")
+
+// IsCapabilitiesUnsupported returns true if err is, or wraps, ErrGetCapabilitiesNotSupported.
+func IsCapabilitiesUnsupported(err error) bool {
+	return errors.Is(err, ErrGetCapabilitiesNotSupported)
+}
+
+// NotificationNotFoundError is returned when operating on a notification the server does not know about,
+// because it was already closed or never sent.
+type NotificationNotFoundError struct {
+	ID uint32
+}
+
+func (e *NotificationNotFoundError) Error() string {
+	return fmt.Sprintf("notify: notification not found: %d", e.ID)
+}
+
+// IsNotFound returns true if err is, or wraps, a *NotificationNotFoundError.
+func IsNotFound(err error) bool {
+	var notFound *NotificationNotFoundError
+	return errors.As(err, &notFound)
+}
+
+// NotificationTooLargeError is returned when a notification is larger than allowed by WithMaxNotificationSize.
+// See TrimToSize.
+type NotificationTooLargeError struct {
+	// Estimated is the size of the notification according to EstimateWireSize
+	Estimated int
+	// Max is the size allowed
+	Max int
+}
+
+func (e *NotificationTooLargeError) Error() string {
+	return fmt.Sprintf("notify: notification too large: estimated %d bytes exceeds %d bytes", e.Estimated, e.Max)
+}
+
+// dbusErrorServiceUnknown is the DBus error name returned when calling a name nobody owns.
+const dbusErrorServiceUnknown = "org.freedesktop.DBus.Error.ServiceUnknown"
+
+// dbusErrorUnknownMethod is the DBus error name returned when calling a method the server does not implement.
+const dbusErrorUnknownMethod = "org.freedesktop.DBus.Error.UnknownMethod"
+
+// ServerNotRunningError is returned when no notification server is running on the bus,
+// e.g. on headless servers and in containers.
+type ServerNotRunningError struct {
+	Cause error
+}
+
+func (e *ServerNotRunningError) Error() string {
+	return fmt.Sprintf("notify: notification server is not running: %v", e.Cause)
+}
+
+func (e *ServerNotRunningError) Unwrap() error {
+	return e.Cause
+}
+
+// IsServerNotRunning returns true if err is, or wraps, a *ServerNotRunningError.
+func IsServerNotRunning(err error) bool {
+	var notRunning *ServerNotRunningError
+	return errors.As(err, &notRunning)
+}
+
+// callError translates well-known DBus errors returned from calls to the server.
+func callError(err error) error {
+	if dbusErrorName(err) == dbusErrorServiceUnknown {
+		return &ServerNotRunningError{Cause: err}
+	}
+	return err
+}
+
+// dbusErrorName returns the name of the DBus error wrapped in err, or "" if err is not a DBus error.
+func dbusErrorName(err error) string {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name
+	}
+	var dbusErrPtr *dbus.Error
+	if errors.As(err, &dbusErrPtr) {
+		return dbusErrPtr.Name
+	}
+	return ""
+}
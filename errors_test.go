@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNotFound(t *testing.T) {
+	require.True(t, IsNotFound(&NotificationNotFoundError{ID: 1}))
+	require.True(t, IsNotFound(fmt.Errorf("closing: %w", &NotificationNotFoundError{ID: 1})))
+	require.False(t, IsNotFound(errors.New("other")))
+	require.False(t, IsNotFound(nil))
+}
+
+func TestDBusErrorName(t *testing.T) {
+	err := dbus.Error{Name: "org.freedesktop.Notifications.InvalidId"}
+	require.Equal(t, "org.freedesktop.Notifications.InvalidId", dbusErrorName(err))
+	require.Equal(t, "org.example.Error", dbusErrorName(fmt.Errorf("wrapped: %w", dbus.NewError("org.example.Error", nil))))
+	require.Equal(t, "", dbusErrorName(errors.New("not dbus")))
+}
+
+func TestCloseNotificationInvalidID(t *testing.T) {
+	// no connection: must return before touching dbus
+	n := newNotifier()
+
+	err := n.CloseNotification(0)
+	require.Equal(t, ErrInvalidNotificationID, err)
+
+	_, err = n.CloseNotificationSync(context.Background(), 0)
+	require.Equal(t, ErrInvalidNotificationID, err)
+}
+
+func TestIsServerNotRunning(t *testing.T) {
+	err := callError(dbus.Error{Name: dbusErrorServiceUnknown})
+	require.True(t, IsServerNotRunning(err))
+	require.True(t, IsServerNotRunning(fmt.Errorf("sending: %w", err)))
+	require.Equal(t, dbusErrorServiceUnknown, dbusErrorName(err))
+
+	other := dbus.Error{Name: "org.example.Error"}
+	require.Equal(t, other, callError(other))
+	require.False(t, IsServerNotRunning(other))
+	require.False(t, IsServerNotRunning(nil))
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// eventRecord is the JSON form of a NotificationEvent written by EventWriter.
+type eventRecord struct {
+	Type   string    `json:"type"`
+	ID     uint32    `json:"id"`
+	Key    string    `json:"key,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	TS     time.Time `json:"ts"`
+}
+
+// eventWriters holds the writers started by EventWriter.
+type eventWriters struct {
+	mu      sync.Mutex
+	writers map[*bufio.Writer]struct{}
+}
+
+// EventWriter starts writing every signal received to w as newline delimited JSON, one object per line:
+//
+//	{"type":"action","id":12,"key":"open","ts":"2024-01-02T15:04:05.999999999Z"}
+//	{"type":"closed","id":12,"reason":"DismissedByUser","ts":"2024-01-02T15:04:05.999999999Z"}
+//
+// Events are written from the signal delivery loop, so a slow w delays signal handlers.
+// Call the returned function to stop writing to w.
+func (n *notifier) EventWriter(w io.Writer) func() {
+	bw := bufio.NewWriter(w)
+	n.events.mu.Lock()
+	if n.events.writers == nil {
+		n.events.writers = map[*bufio.Writer]struct{}{}
+	}
+	n.events.writers[bw] = struct{}{}
+	n.events.mu.Unlock()
+
+	stop := sync.Once{}
+	return func() {
+		stop.Do(func() {
+			n.events.mu.Lock()
+			defer n.events.mu.Unlock()
+			delete(n.events.writers, bw)
+		})
+	}
+}
+
+// writeEvent writes e to all writers started by EventWriter.
+func (n *notifier) writeEvent(e NotificationEvent, ts time.Time) {
+	n.events.mu.Lock()
+	defer n.events.mu.Unlock()
+	if len(n.events.writers) == 0 {
+		return
+	}
+
+	record := eventRecord{ID: e.ID, TS: ts}
+	switch {
+	case e.IsClosed():
+		record.Type = "closed"
+		record.Reason = e.Closed.Reason.String()
+	case e.IsAction():
+		record.Type = "action"
+		record.Key = e.Action.ActionKey
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		n.log.Printf("Error encoding event: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	for bw := range n.events.writers {
+		if _, err := bw.Write(line); err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			n.log.Printf("Error writing event: %v", err)
+		}
+	}
+}
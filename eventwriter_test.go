@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventWriter(t *testing.T) {
+	n := newNotifier(WithLogger(discardLogger{}))
+	buf := &bytes.Buffer{}
+	stop := n.EventWriter(buf)
+
+	n.handleSignal(&dbus.Signal{Name: n.endpoint.member(signalActionInvoked), Body: []interface{}{uint32(12), "open"}})
+	n.handleSignal(&dbus.Signal{Name: n.endpoint.member(signalNotificationClosed), Body: []interface{}{uint32(12), uint32(ReasonDismissedByUser)}})
+	stop()
+	stop()
+	n.handleSignal(&dbus.Signal{Name: n.endpoint.member(signalNotificationClosed), Body: []interface{}{uint32(13), uint32(ReasonExpired)}})
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event), scanner.Text())
+		require.NotEmpty(t, event["ts"])
+		delete(event, "ts")
+		events = append(events, event)
+	}
+	require.Equal(t, []map[string]interface{}{
+		{"type": "action", "id": float64(12), "key": "open"},
+		{"type": "closed", "id": float64(12), "reason": "DismissedByUser"},
+	}, events)
+}
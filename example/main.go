@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/draw"
@@ -9,7 +10,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -27,22 +27,12 @@ func main() {
 }
 
 func runMain() error {
-	wg := &sync.WaitGroup{}
-
-	conn, err := dbus.SessionBusPrivate()
+	conn, err := notify.GetDefaultSession()
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
 
-	if err = conn.Auth(nil); err != nil {
-		panic(err)
-	}
-
-	if err = conn.Hello(); err != nil {
-		panic(err)
-	}
-
 	DebugServerFeatures(conn)
 
 	// Basic usage
@@ -68,20 +58,18 @@ func runMain() error {
 		},
 		ExpireTimeout: time.Second * 5,
 	}
-	n.SetUrgency(notify.UrgencyCritical)
+	n.Urgency = notify.UrgencyCritical.Ptr()
 
 	counter := int32(0)
 	// Listen for actions invoked!
 	onAction := func(action *notify.ActionInvokedSignal) {
 		atomic.AddInt32(&counter, 1)
 		log.Printf("ActionInvoked: %v Key: %v", action.ID, action.ActionKey)
-		wg.Done()
 	}
 
 	onClosed := func(closer *notify.NotificationClosedSignal) {
 		atomic.AddInt32(&counter, 1)
 		log.Printf("NotificationClosed: %v Reason: %v", closer.ID, closer.Reason)
-		wg.Done()
 	}
 
 	// Notifier instance with event delivery:
@@ -116,17 +104,17 @@ func runMain() error {
 	// according to spec, image-data hint should have precedence:
 	n.AddHint(hintProfileImage)
 
-	wg.Add(1)
-	id, err := notifier.SendNotification(n)
+	// the waiter counts one signal per notification, even though Gnome delivers multiple copies of the action signal
+	waiter := notify.NewSignalWaiter(notifier)
+	id, err := waiter.Add(n)
 	if err != nil {
 		log.Printf("error sending notification: %v", err)
 	}
 	log.Printf("sent notification id: %v", id)
 
-	// add two extra so the process should block forever:
-	// this is due to a bug in Gnome that delivers multiple copies of the action signal...
-	wg.Add(2)
-	wg.Wait()
+	if err := waiter.Wait(context.Background()); err != nil {
+		return err
+	}
 
 	log.Printf("total signal count received: %d", atomic.LoadInt32(&counter))
 
@@ -147,10 +135,9 @@ func DebugServerFeatures(conn *dbus.Conn) {
 	if err != nil {
 		log.Printf("error getting server information: %v", err)
 	}
-	fmt.Printf("Name:    %v\n", info.Name)
-	fmt.Printf("Vendor:  %v\n", info.Vendor)
-	fmt.Printf("Version: %v\n", info.Version)
-	fmt.Printf("Spec:    %v\n", info.SpecVersion)
+	if !info.IsUnknown() {
+		fmt.Printf("Server: %v\n", info)
+	}
 }
 
 func readImage(path string) (*image.RGBA, error) {
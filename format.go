@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// String returns the name of u, e.g. "Critical".
+func (u Urgency) String() string {
+	switch u {
+	case UrgencyLow:
+		return "Low"
+	case UrgencyNormal:
+		return "Normal"
+	case UrgencyCritical:
+		return "Critical"
+	default:
+		return fmt.Sprintf("Urgency(%d)", byte(u))
+	}
+}
+
+// String formats a human readable summary of n, e.g.:
+//
+//	[app] "Summary": "Body" (expire: 5s, urgency: Critical, hints: 2)
+func (n Notification) String() string {
+	expire := n.ExpireTimeout.String()
+	switch n.ExpireTimeout {
+	case ExpireTimeoutNever:
+		expire = "never"
+	case ExpireTimeoutSetByNotificationServer:
+		expire = "server default"
+	}
+	urgency := "unset"
+	if u, ok := GetUrgency(n); ok {
+		urgency = u.String()
+	}
+	return fmt.Sprintf("[%s] %q: %q (expire: %s, urgency: %s, hints: %d)",
+		n.AppName, n.Summary, n.Body, expire, urgency, len(n.Hints))
+}
+
+// GoString formats n as Go syntax, for use with %#v.
+// Hints are sorted by key, and hint values are converted to their exact type.
+func (n Notification) GoString() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "notify.Notification{AppName:%#v, ReplacesID:%#v, AppIcon:%#v, Summary:%#v, Body:%#v, Actions:%#v, Hints:",
+		n.AppName, n.ReplacesID, n.AppIcon, n.Summary, n.Body, n.Actions)
+	if n.Hints == nil {
+		sb.WriteString("map[string]dbus.Variant(nil)")
+	} else {
+		keys := make([]string, 0, len(n.Hints))
+		for k := range n.Hints {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		sb.WriteString("map[string]dbus.Variant{")
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			value := n.Hints[k].Value()
+			fmt.Fprintf(sb, "%#v:dbus.MakeVariant(%T(%#v))", k, value, value)
+		}
+		sb.WriteString("}")
+	}
+	if n.Urgency == nil {
+		sb.WriteString(", Urgency:(*notify.Urgency)(nil)")
+	} else {
+		fmt.Fprintf(sb, ", Urgency:notify.Urgency(%#v).Ptr()", byte(*n.Urgency))
+	}
+	fmt.Fprintf(sb, ", ExpireTimeout:%d}", int64(n.ExpireTimeout))
+	return sb.String()
+}
+
+// String formats s, e.g.: NotificationClosed{ID:12, Reason:DismissedByUser}
+func (s *NotificationClosedSignal) String() string {
+	return fmt.Sprintf("NotificationClosed{ID:%d, Reason:%s}", s.ID, s.Reason)
+}
+
+// String formats s, e.g.: ActionInvoked{ID:12, Key:"open"}
+func (s *ActionInvokedSignal) String() string {
+	return fmt.Sprintf("ActionInvoked{ID:%d, Key:%q}", s.ID, s.ActionKey)
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func formatTestNotifications() []Notification {
+	full := Notification{
+		AppName:       "app",
+		ReplacesID:    7,
+		AppIcon:       "mail-unread",
+		Summary:       "Summary",
+		Body:          "Body with \"quotes\"",
+		Actions:       []Action{{Key: "open", Label: "Open"}},
+		ExpireTimeout: 5 * time.Second,
+	}
+	full.SetUrgency(UrgencyCritical)
+	full.AddHint(HintSoundWithName("bell"))
+
+	return []Notification{
+		{},
+		{Summary: "server default", ExpireTimeout: ExpireTimeoutSetByNotificationServer},
+		full,
+	}
+}
+
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, []byte(got), 0644))
+	}
+	want, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), got)
+}
+
+func TestNotificationString(t *testing.T) {
+	sb := &strings.Builder{}
+	for _, n := range formatTestNotifications() {
+		fmt.Fprintln(sb, n.String())
+	}
+	assertGolden(t, "notification_string.golden", sb.String())
+}
+
+func TestNotificationGoString(t *testing.T) {
+	sb := &strings.Builder{}
+	for _, n := range formatTestNotifications() {
+		fmt.Fprintf(sb, "%#v\n", n)
+	}
+	assertGolden(t, "notification_gostring.golden", sb.String())
+}
+
+func TestNotificationClosedSignalString(t *testing.T) {
+	cases := map[Reason]string{
+		ReasonExpired:         "NotificationClosed{ID:12, Reason:Expired}",
+		ReasonDismissedByUser: "NotificationClosed{ID:12, Reason:DismissedByUser}",
+		ReasonClosedByCall:    "NotificationClosed{ID:12, Reason:ClosedByCall}",
+		ReasonUnknown:         "NotificationClosed{ID:12, Reason:Unknown}",
+		Reason(42):            "NotificationClosed{ID:12, Reason:Other}",
+	}
+	for reason, want := range cases {
+		s := &NotificationClosedSignal{ID: 12, Reason: reason}
+		require.Equal(t, want, s.String())
+		require.Equal(t, want, fmt.Sprint(s))
+	}
+}
+
+func TestActionInvokedSignalString(t *testing.T) {
+	s := &ActionInvokedSignal{ID: 12, ActionKey: "open"}
+	require.Equal(t, `ActionInvoked{ID:12, Key:"open"}`, s.String())
+	require.Equal(t, `ActionInvoked{ID:12, Key:"open"}`, fmt.Sprint(s))
+}
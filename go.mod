@@ -5,4 +5,5 @@ go 1.13
 require (
 	github.com/godbus/dbus/v5 v5.1.0
 	github.com/stretchr/testify v1.8.4
+	gopkg.in/yaml.v3 v3.0.1
 )
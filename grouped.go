@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// GroupedNotifier is a Notifier that stacks notifications of the same group into a single notification,
+// instead of showing one notification each.
+// It wraps a base Notifier, which is left open when the GroupedNotifier is closed.
+//
+// Only SendNotification groups notifications, the other methods go straight to the base Notifier.
+type GroupedNotifier struct {
+	Notifier
+
+	groupKey     func(Notification) string
+	maxBodyBytes int
+
+	// mu guards groups, each group has its own lock for sending
+	mu     sync.Mutex
+	groups map[string]*notificationGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// notificationGroup is the notification currently shown for a group key.
+// mu is held while sending, so notifications of the same group are sent one at a time.
+type notificationGroup struct {
+	mu   sync.Mutex
+	id   uint32
+	body string
+	// closed is set once the notification was closed, and the group removed from GroupedNotifier.groups
+	closed bool
+}
+
+// NewGroupedNotifier creates a GroupedNotifier sending through base.
+//
+// groupKey returns the group of a notification. Notifications with an empty group key are not grouped.
+// The body of a grouped notification is limited to maxBodyBytes, dropping the oldest lines first.
+// A maxBodyBytes of 0 or less means MaxBodyBytes.
+func NewGroupedNotifier(base Notifier, groupKey func(Notification) string, maxBodyBytes int) *GroupedNotifier {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = MaxBodyBytes
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &GroupedNotifier{
+		Notifier:     base,
+		groupKey:     groupKey,
+		maxBodyBytes: maxBodyBytes,
+		groups:       map[string]*notificationGroup{},
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Unwrap returns the base Notifier of g.
+func (g *GroupedNotifier) Unwrap() Notifier {
+	return g.Notifier
+}
+
+// SendNotification sends n, or if a notification of the same group is still shown, replaces it with n,
+// keeping the body of the shown notification with the body of n appended on a new line.
+// If n has no body, its summary is used instead.
+//
+// When the notification of a group is closed, the next notification of the group is sent as a new notification.
+// Notifications of different groups are sent concurrently.
+func (g *GroupedNotifier) SendNotification(n Notification) (uint32, error) {
+	key := g.groupKey(n)
+	if key == "" {
+		return g.Notifier.SendNotification(n)
+	}
+
+	group := g.lockGroup(key)
+	defer group.mu.Unlock()
+
+	line := n.Body
+	if line == "" {
+		line = n.Summary
+	}
+	body := line
+	if group.body != "" {
+		body = group.body + "\n" + line
+	}
+	if group.id != 0 {
+		n.ReplacesID = group.id
+	}
+	n.Body = trimOldestLines(body, g.maxBodyBytes)
+
+	id, err := g.Notifier.SendNotification(n)
+	if err != nil {
+		return id, err
+	}
+	if group.id != id {
+		// a new notification, either the first of the group, or the shown one was closed meanwhile
+		group.id = id
+		g.watch(key, group, id)
+	}
+	group.body = n.Body
+	return id, nil
+}
+
+// lockGroup returns the group of key, creating it if needed, with its lock held.
+func (g *GroupedNotifier) lockGroup(key string) *notificationGroup {
+	for {
+		g.mu.Lock()
+		group, ok := g.groups[key]
+		if !ok {
+			group = &notificationGroup{}
+			g.groups[key] = group
+		}
+		g.mu.Unlock()
+
+		group.mu.Lock()
+		if !group.closed {
+			return group
+		}
+		// closed while waiting for the lock, look up the group of key again
+		group.mu.Unlock()
+	}
+}
+
+// watch forgets group once the notification with id is closed, unless the group moved on to another notification.
+func (g *GroupedNotifier) watch(key string, group *notificationGroup, id uint32) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		for {
+			e, err := g.Notifier.WaitForSignal(g.ctx, id)
+			if err != nil {
+				return
+			}
+			if e.IsClosed() {
+				break
+			}
+		}
+		group.mu.Lock()
+		defer group.mu.Unlock()
+		if group.id != id {
+			return
+		}
+		group.closed = true
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.groups[key] == group {
+			delete(g.groups, key)
+		}
+	}()
+}
+
+// Close stops tracking groups. The base Notifier is left open.
+// It is safe to be called multiple times.
+func (g *GroupedNotifier) Close() error {
+	g.cancel()
+	g.wg.Wait()
+	return nil
+}
+
+// Shutdown stops tracking groups like Close. The base Notifier is left open.
+func (g *GroupedNotifier) Shutdown(ctx context.Context) error {
+	return g.Close()
+}
+
+// trimOldestLines drops lines from the start of s until it is at most maxBytes long.
+// If the last line alone is too long, it is truncated.
+func trimOldestLines(s string, maxBytes int) string {
+	for len(s) > maxBytes {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return truncateString(s, maxBytes, "…")
+		}
+		s = s[i+1:]
+	}
+	return s
+}
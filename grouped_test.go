@@ -0,0 +1,13 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimOldestLines(t *testing.T) {
+	require.Equal(t, "a\nb", trimOldestLines("a\nb", 3))
+	require.Equal(t, "b\nc", trimOldestLines("a\nb\nc", 3))
+	require.Equal(t, "ab…", trimOldestLines("old\nabcdef", len("ab…")))
+}
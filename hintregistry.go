@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// vendorHintPrefix starts the keys of vendor specific hints, which the spec leaves to each vendor to define.
+const vendorHintPrefix = "x-"
+
+// HintRegistry maps hint keys to the DBus type their value must have, as a type signature such as "s".
+// It is safe for concurrent use.
+type HintRegistry struct {
+	mu    sync.RWMutex
+	hints map[string]registeredHint
+}
+
+type registeredHint struct {
+	dbusType    string
+	description string
+}
+
+// NewHintRegistry creates an empty HintRegistry.
+func NewHintRegistry() *HintRegistry {
+	return &HintRegistry{hints: map[string]registeredHint{}}
+}
+
+// BuiltinRegistry returns a new HintRegistry with the hints defined by the spec,
+// and the hints of this package not defined by the spec, such as "value" of HintProgress.
+// The returned registry can be extended with Register.
+func BuiltinRegistry() *HintRegistry {
+	r := NewHintRegistry()
+	r.Register("action-icons", "b", "action keys are icon names")
+	r.Register("category", "s", "type of notification")
+	r.Register("desktop-entry", "s", "name of the desktop file of the sending application")
+	r.Register("image-data", "(iiibiiay)", "raw image data")
+	r.Register("image_data", "(iiibiiay)", "raw image data, deprecated name")
+	r.Register("icon_data", "(iiibiiay)", "raw image data, deprecated name")
+	r.Register("image-path", "s", "image file path or icon name")
+	r.Register("image_path", "s", "image file path or icon name, deprecated name")
+	r.Register("resident", "b", "keep the notification after an action is invoked")
+	r.Register("sound-file", "s", "path to a sound file to play")
+	r.Register("sound-name", "s", "themeable sound name to play")
+	r.Register("suppress-sound", "b", "do not play any sound")
+	r.Register("transient", "b", "bypass the server's persistence capability")
+	r.Register("x", "i", "x location on the screen to point to")
+	r.Register("y", "i", "y location on the screen to point to")
+	r.Register("urgency", "y", "urgency level")
+	r.Register("value", "i", "progress percentage, not in the spec but widely supported")
+	r.Register("sound-data", "(uqay)", "raw sound data, not in the spec")
+	r.Register("timestamp", "u", "Unix seconds the notification happened at, not in the spec")
+	return r
+}
+
+// builtinRegistry is checked by Notification.Validate.
+var builtinRegistry = BuiltinRegistry()
+
+// Register sets the DBus type signature of the hint with key, replacing a previous registration.
+func (r *HintRegistry) Register(key, dbusType string, description string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hints[key] = registeredHint{dbusType: dbusType, description: description}
+}
+
+// Validate checks the type of each of hints against the registry.
+// It returns a *ValidationError for the first hint of the wrong type, in order of keys.
+// If all types match, a *ValidationWarning is returned for the first hint not in the registry.
+// Vendor specific hints, with keys starting with "x-", are never reported as unknown.
+func (r *HintRegistry) Validate(hints map[string]dbus.Variant) error {
+	keys := make([]string, 0, len(hints))
+	for k := range hints {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	unknown := ""
+	for _, k := range keys {
+		hint, ok := r.hints[k]
+		if !ok {
+			if unknown == "" && !strings.HasPrefix(k, vendorHintPrefix) {
+				unknown = k
+			}
+			continue
+		}
+		if got := hints[k].Signature().String(); got != hint.dbusType {
+			return &ValidationError{
+				Field:  fmt.Sprintf("Hints[%q]", k),
+				Reason: fmt.Sprintf("must have type %s (%s), got %s", hint.dbusType, hint.description, got),
+			}
+		}
+	}
+	if unknown != "" {
+		return &ValidationWarning{Field: fmt.Sprintf("Hints[%q]", unknown), Reason: "unknown hint"}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// HintSet is a set of hints keyed by hint ID, to be applied to notifications.
+type HintSet map[string]dbus.Variant
+
+// NewHintSet creates a HintSet from hints. Later hints overwrite earlier hints with the same ID.
+func NewHintSet(hints ...HintProvider) HintSet {
+	hs := make(HintSet, len(hints))
+	for _, provider := range hints {
+		h := provider.ToHint()
+		hs[h.ID] = h.Variant
+	}
+	return hs
+}
+
+// ApplyTo merges hs into the hints of n, overwriting hints with the same ID.
+// Other hints of n are kept.
+func (hs HintSet) ApplyTo(n *Notification) {
+	if n.Hints == nil {
+		n.Hints = make(map[string]dbus.Variant, len(hs))
+	}
+	for k, v := range hs {
+		n.Hints[k] = v
+	}
+}
+
+// Remove returns a copy of hs without the hints with keys.
+func (hs HintSet) Remove(keys ...string) HintSet {
+	removed := make(HintSet, len(hs))
+	for k, v := range hs {
+		removed[k] = v
+	}
+	for _, k := range keys {
+		delete(removed, k)
+	}
+	return removed
+}
+
+// ApplyHints adds hints to n, like calling AddHint for each of them, and returns n.
+func (n *Notification) ApplyHints(hints ...HintProvider) *Notification {
+	for _, h := range hints {
+		n.AddHint(h)
+	}
+	return n
+}
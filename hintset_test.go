@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHintSet(t *testing.T) {
+	hs := NewHintSet(HintUrgency(UrgencyCritical), HintSoundWithName("bell"))
+	require.Len(t, hs, 2)
+
+	n := Notification{}
+	n.AddHint(HintFromCategory(CategoryEmail))
+	hs.ApplyTo(&n)
+	// existing hints are merged with, not replaced
+	require.Len(t, n.Hints, 3)
+	require.Equal(t, byte(UrgencyCritical), n.Hints["urgency"].Value())
+
+	withoutSound := hs.Remove("sound-name", "missing")
+	require.Len(t, withoutSound, 1)
+	require.Len(t, hs, 2)
+
+	var empty Notification
+	withoutSound.ApplyTo(&empty)
+	require.Len(t, empty.Hints, 1)
+
+	n2 := Notification{}
+	require.Equal(t, &n2, n2.ApplyHints(HintUrgency(UrgencyLow), HintFromCategory(CategoryIM)))
+	require.Len(t, n2.Hints, 2)
+}
@@ -0,0 +1,219 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// historyEntryOverhead approximates the memory used by a HistoryEntry besides its notification.
+const historyEntryOverhead = 128
+
+// HistoryEntry records a notification sent by a Notifier, see WithHistory.
+type HistoryEntry struct {
+	// ID returned by the server
+	ID uint32
+	// Notification as sent
+	Notification Notification
+	// SentAt is the time the notification was sent
+	SentAt time.Time
+	// ClosedAt is the time the NotificationClosed signal was received, or nil while open
+	ClosedAt *time.Time
+	// CloseReason is the reason of the NotificationClosed signal, or nil while open
+	CloseReason *Reason
+}
+
+// WithHistory makes the Notifier remember the last size notifications sent, see Notifier.History.
+// Memory for size entries is allocated once.
+func WithHistory(size int) option {
+	return func(n *notifier) {
+		if size > 0 {
+			n.history = newHistory(size)
+		}
+	}
+}
+
+// WithHistoryEvictionTTL evicts entries sent more than ttl ago from the history of WithHistory,
+// releasing the memory of their notifications. Entries are checked every ttl/2, but at most once per millisecond,
+// and ttl must not be negative.
+// Without this option, or with ttl of 0, entries are only evicted to make room for new entries.
+func WithHistoryEvictionTTL(ttl time.Duration) option {
+	return func(n *notifier) {
+		n.historyTTL = ttl
+	}
+}
+
+// WithMaxHistoryMemory evicts the oldest entries from the history of WithHistory
+// while its estimated memory use exceeds bytes. The newest entry is always kept.
+// Memory use is estimated from EstimateWireSize of each notification, plus a constant per entry.
+// Without this option, or with bytes of 0, the history is only limited by its size. bytes must not be negative.
+func WithMaxHistoryMemory(bytes int) option {
+	return func(n *notifier) {
+		n.historyMaxMemory = bytes
+	}
+}
+
+// WithOnEvict calls h with each entry removed from the history of WithHistory,
+// whether it is evicted by WithHistoryEvictionTTL, WithMaxHistoryMemory or overwritten by a newer entry,
+// e.g. to persist it elsewhere.
+// h is called after the entry is removed, and must not block for long, as sending a notification waits for it.
+func WithOnEvict(h func(HistoryEntry)) option {
+	return func(n *notifier) {
+		n.onEvict = h
+	}
+}
+
+// History returns the notifications remembered by WithHistory, oldest first.
+// Returns nil if WithHistory is not used.
+func (n *notifier) History() []HistoryEntry {
+	return n.history.snapshot()
+}
+
+// evictHistoryLoop evicts history entries older than n.historyTTL until done is closed.
+func (n *notifier) evictHistoryLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(expiryInterval(n.historyTTL))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			n.history.expire(now.Add(-n.historyTTL))
+		case <-done:
+			return
+		}
+	}
+}
+
+// validateHistory checks the history options.
+func (n *notifier) validateHistory() error {
+	if n.historyTTL < 0 {
+		return fmt.Errorf("invalid history eviction TTL: %v", n.historyTTL)
+	}
+	if n.historyMaxMemory < 0 {
+		return fmt.Errorf("invalid max history memory: %d", n.historyMaxMemory)
+	}
+	return nil
+}
+
+// history is a fixed size ring buffer of sent notifications.
+// A nil *history records nothing.
+type history struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	// first is the index of the oldest entry
+	first int
+	count int
+	// memory is the estimated memory use of the entries
+	memory int
+
+	// maxMemory evicts the oldest entries while memory exceeds it, unless 0
+	maxMemory int
+	// onEvict is called with evicted entries, unless nil
+	onEvict func(HistoryEntry)
+}
+
+func newHistory(size int) *history {
+	return &history{
+		entries: make([]HistoryEntry, size),
+	}
+}
+
+func (h *history) add(id uint32, note Notification, now time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	var evicted []HistoryEntry
+	if h.count == len(h.entries) {
+		evicted = append(evicted, h.removeOldest())
+	}
+	e := HistoryEntry{ID: id, Notification: note.Clone(), SentAt: now}
+	h.entries[(h.first+h.count)%len(h.entries)] = e
+	h.count++
+	h.memory += historyEntrySize(e)
+	for h.maxMemory > 0 && h.memory > h.maxMemory && h.count > 1 {
+		evicted = append(evicted, h.removeOldest())
+	}
+	h.mu.Unlock()
+
+	h.evicted(evicted)
+}
+
+// expire evicts entries sent before cutoff.
+func (h *history) expire(cutoff time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	var evicted []HistoryEntry
+	for h.count > 0 && h.entries[h.first].SentAt.Before(cutoff) {
+		evicted = append(evicted, h.removeOldest())
+	}
+	h.mu.Unlock()
+
+	h.evicted(evicted)
+}
+
+// removeOldest removes and returns the oldest entry. Caller must hold h.mu, and there must be an entry.
+func (h *history) removeOldest() HistoryEntry {
+	e := h.entries[h.first]
+	// release the notification for the garbage collector
+	h.entries[h.first] = HistoryEntry{}
+	h.first = (h.first + 1) % len(h.entries)
+	h.count--
+	h.memory -= historyEntrySize(e)
+	return e
+}
+
+// evicted calls onEvict with entries, without holding h.mu.
+func (h *history) evicted(entries []HistoryEntry) {
+	if h.onEvict == nil {
+		return
+	}
+	for _, e := range entries {
+		h.onEvict(e)
+	}
+}
+
+// closed marks the most recent open entry with the ID of s as closed.
+func (h *history) closed(s *NotificationClosedSignal, now time.Time) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := h.count - 1; i >= 0; i-- {
+		e := &h.entries[(h.first+i)%len(h.entries)]
+		if e.ID == s.ID && e.ClosedAt == nil {
+			closedAt, reason := now, s.Reason
+			e.ClosedAt = &closedAt
+			e.CloseReason = &reason
+			return
+		}
+	}
+}
+
+func (h *history) snapshot() []HistoryEntry {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]HistoryEntry, 0, h.count)
+	for i := 0; i < h.count; i++ {
+		e := h.entries[(h.first+i)%len(h.entries)]
+		e.Notification = e.Notification.Clone()
+		if e.ClosedAt != nil {
+			closedAt, reason := *e.ClosedAt, *e.CloseReason
+			e.ClosedAt, e.CloseReason = &closedAt, &reason
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// historyEntrySize estimates the memory used by e.
+func historyEntrySize(e HistoryEntry) int {
+	return EstimateWireSize(e.Notification) + historyEntryOverhead
+}
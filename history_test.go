@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRingBuffer(t *testing.T) {
+	n := newNotifier(WithHistory(3))
+	now := time.Now()
+	for id := uint32(1); id <= 4; id++ {
+		n.history.add(id, Notification{Summary: "note"}, now)
+	}
+
+	entries := n.History()
+	require.Len(t, entries, 3)
+	// the oldest entry is overwritten first
+	require.Equal(t, []uint32{2, 3, 4}, []uint32{entries[0].ID, entries[1].ID, entries[2].ID})
+	require.Nil(t, entries[0].ClosedAt)
+	require.Nil(t, entries[0].CloseReason)
+
+	n.history.closed(&NotificationClosedSignal{ID: 3, Reason: ReasonDismissedByUser}, now.Add(time.Second))
+	// unknown ids are ignored
+	n.history.closed(&NotificationClosedSignal{ID: 1, Reason: ReasonExpired}, now)
+
+	entries = n.History()
+	require.NotNil(t, entries[1].ClosedAt)
+	require.Equal(t, now.Add(time.Second), *entries[1].ClosedAt)
+	require.Equal(t, ReasonDismissedByUser, *entries[1].CloseReason)
+	require.Nil(t, entries[2].ClosedAt)
+
+	// snapshots do not share state with the history
+	entries[0].Notification.Summary = "changed"
+	require.Equal(t, "note", n.History()[0].Notification.Summary)
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	n := newNotifier()
+	n.history.add(1, Notification{}, time.Now())
+	n.history.closed(&NotificationClosedSignal{ID: 1}, time.Now())
+	require.Nil(t, n.History())
+}
+
+func TestHistoryEviction(t *testing.T) {
+	var evicted []uint32
+	onEvict := WithOnEvict(func(e HistoryEntry) { evicted = append(evicted, e.ID) })
+	now := time.Now()
+
+	t.Run("overwritten", func(t *testing.T) {
+		evicted = nil
+		n := newNotifier(WithHistory(2), onEvict)
+		for id := uint32(1); id <= 3; id++ {
+			n.history.add(id, Notification{}, now)
+		}
+		require.Equal(t, []uint32{1}, evicted)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		evicted = nil
+		n := newNotifier(WithHistory(4), WithHistoryEvictionTTL(time.Minute), onEvict)
+		n.history.add(1, Notification{}, now.Add(-2*time.Minute))
+		n.history.add(2, Notification{}, now.Add(-time.Second))
+		n.history.expire(now.Add(-time.Minute))
+
+		require.Equal(t, []uint32{1}, evicted)
+		entries := n.History()
+		require.Len(t, entries, 1)
+		require.Equal(t, uint32(2), entries[0].ID)
+
+		// the ring buffer keeps working after evicting
+		for id := uint32(3); id <= 6; id++ {
+			n.history.add(id, Notification{}, now)
+		}
+		require.Equal(t, []uint32{1, 2}, evicted)
+		require.Len(t, n.History(), 4)
+		require.Equal(t, uint32(3), n.History()[0].ID)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		evicted = nil
+		note := Notification{Body: strings.Repeat("a", 1000)}
+		size := historyEntrySize(HistoryEntry{Notification: note})
+		n := newNotifier(WithHistory(10), WithMaxHistoryMemory(2*size), onEvict)
+		for id := uint32(1); id <= 3; id++ {
+			n.history.add(id, note, now)
+		}
+		require.Equal(t, []uint32{1}, evicted)
+		require.Len(t, n.History(), 2)
+
+		// the newest entry is kept, even if it alone is too large
+		n.history.add(4, Notification{Body: strings.Repeat("a", 3*size)}, now)
+		require.Equal(t, []uint32{1, 2, 3}, evicted)
+		require.Len(t, n.History(), 1)
+	})
+}
+
+func TestHistoryEvictionOptionsValidated(t *testing.T) {
+	require.Error(t, newNotifier(WithHistoryEvictionTTL(-time.Second)).validateHistory())
+	require.Error(t, newNotifier(WithMaxHistoryMemory(-1)).validateHistory())
+}
+
+func TestHistoryEvictionTinyTTL(t *testing.T) {
+	n := newNotifier(WithHistory(1), WithHistoryEvictionTTL(time.Nanosecond))
+	n.group.Start(n.evictHistoryLoop)
+	// half of 1ns rounds down to 0, which must not make the ticker panic
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, n.group.Stop(func() error { return nil }))
+}
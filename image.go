@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedImageSource is returned by HintImage for sources of unknown type.
+var ErrUnsupportedImageSource = errors.New("notify: unsupported image source")
+
+// HintImage creates an image hint from src, which may be one of:
+//
+//   - string: an absolute file path or a file:// URI, sent as "image-path".
+//     Relative paths and other URI schemes are not supported.
+//   - *image.RGBA: sent inline as "image-data"
+//   - image.Image: converted to RGBA and sent inline as "image-data"
+//   - []byte: an encoded image, e.g. PNG or JPEG, decoded and sent inline as "image-data".
+//     The format must be registered with the image package, e.g. by importing image/png.
+//
+// Inline image data is preferred over paths where possible, as the server may not be able to read
+// the path, e.g. when running in a sandbox. Per the spec, "image-data" also takes precedence over "image-path".
+func HintImage(src interface{}) (Hint, error) {
+	switch v := src.(type) {
+	case string:
+		path, err := imageFilePath(v)
+		if err != nil {
+			return Hint{}, err
+		}
+		return HintImageFilePath(path), nil
+	case *image.RGBA:
+		return HintImageDataRGBA(v), nil
+	case image.Image:
+		return HintImageDataRGBA(toRGBA(v)), nil
+	case []byte:
+		contentType := http.DetectContentType(v)
+		if !strings.HasPrefix(contentType, "image/") {
+			return Hint{}, fmt.Errorf("%w: content type %v", ErrUnsupportedImageSource, contentType)
+		}
+		img, _, err := image.Decode(bytes.NewReader(v))
+		if err != nil {
+			return Hint{}, fmt.Errorf("error decoding %v: %w", contentType, err)
+		}
+		return HintImageDataRGBA(toRGBA(img)), nil
+	default:
+		return Hint{}, fmt.Errorf("%w: %T", ErrUnsupportedImageSource, src)
+	}
+}
+
+// WithIcon returns a function setting the icon of a notification from src, which may be one of:
+//
+//   - string: an icon name if it contains no '/', set as AppIcon. Otherwise an absolute file path
+//     or a file:// URI, set as AppIcon as a file:// URI.
+//   - *image.RGBA, image.Image or []byte: an image, added with the "image-data" hint as for HintImage.
+//
+// Returns an error if src is of another type, or not a valid path or image.
+//
+//	setIcon, err := notify.WithIcon(iconSource)
+//	if err != nil {
+//		return err
+//	}
+//	setIcon(&n)
+func WithIcon(src interface{}) (func(*Notification), error) {
+	if name, ok := src.(string); ok {
+		icon := name
+		if strings.Contains(name, "/") {
+			path, err := imageFilePath(name)
+			if err != nil {
+				return nil, err
+			}
+			icon = "file://" + path
+		}
+		return func(n *Notification) {
+			n.AppIcon = icon
+		}, nil
+	}
+	hint, err := HintImage(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(n *Notification) {
+		n.AddHint(hint)
+	}, nil
+}
+
+// imageFilePath returns the absolute file path of src, which is either a path or a file:// URI.
+func imageFilePath(src string) (string, error) {
+	path := src
+	if strings.Contains(src, "://") {
+		u, err := url.Parse(src)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnsupportedImageSource, err)
+		}
+		if u.Scheme != "file" {
+			return "", fmt.Errorf("%w: unsupported scheme %q", ErrUnsupportedImageSource, u.Scheme)
+		}
+		path = u.Path
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: not an absolute path: %q", ErrUnsupportedImageSource, src)
+	}
+	return path, nil
+}
+
+// toRGBA returns img as *image.RGBA, converting it if needed.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHintImage(t *testing.T) {
+	hint, err := HintImage("/tmp/icon.png")
+	require.NoError(t, err)
+	require.Equal(t, "image-path", hint.ID)
+	require.Equal(t, "file:///tmp/icon.png", hint.Variant.Value())
+
+	hint, err = HintImage("file:///tmp/icon.png")
+	require.NoError(t, err)
+	require.Equal(t, "file:///tmp/icon.png", hint.Variant.Value())
+
+	hint, err = HintImage("file:///tmp/my%20icon.png")
+	require.NoError(t, err)
+	require.Equal(t, "file:///tmp/my icon.png", hint.Variant.Value())
+
+	for _, src := range []string{"icon.png", "./icons/icon.png", "https://example.com/icon.png", "file://icon.png"} {
+		_, err = HintImage(src)
+		require.True(t, errors.Is(err, ErrUnsupportedImageSource), src)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	hint, err = HintImage(rgba)
+	require.NoError(t, err)
+	require.Equal(t, "image-data", hint.ID)
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 3))
+	gray.Set(1, 1, color.White)
+	hint, err = HintImage(gray)
+	require.NoError(t, err)
+	data := hint.Variant.Value().(ImageData)
+	require.NoError(t, data.Valid())
+	require.EqualValues(t, 3, data.Height)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, gray))
+	hint, err = HintImage(buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, data, hint.Variant.Value().(ImageData))
+
+	_, err = HintImage([]byte("plain text"))
+	require.True(t, errors.Is(err, ErrUnsupportedImageSource))
+	_, err = HintImage(42)
+	require.True(t, errors.Is(err, ErrUnsupportedImageSource))
+}
+
+func TestWithIcon(t *testing.T) {
+	n := Notification{}
+	setIcon, err := WithIcon("mail-unread")
+	require.NoError(t, err)
+	setIcon(&n)
+	require.Equal(t, "mail-unread", n.AppIcon)
+
+	setIcon, err = WithIcon("/tmp/icon.png")
+	require.NoError(t, err)
+	setIcon(&n)
+	require.Equal(t, "file:///tmp/icon.png", n.AppIcon)
+	require.Nil(t, n.Hints)
+
+	setIcon, err = WithIcon(image.NewGray(image.Rect(0, 0, 2, 2)))
+	require.NoError(t, err)
+	setIcon(&n)
+	require.IsType(t, ImageData{}, n.Hints["image-data"].Value())
+
+	_, err = WithIcon("icons/icon.png")
+	require.True(t, errors.Is(err, ErrUnsupportedImageSource))
+	_, err = WithIcon(42)
+	require.True(t, errors.Is(err, ErrUnsupportedImageSource))
+}
@@ -0,0 +1,1521 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esiqveland/notify"
+	"github.com/esiqveland/notify/notifytest"
+)
+
+// startFakeDaemon starts a fake notification server and connects to it.
+// The test is skipped if dbus-daemon is not available.
+func startFakeDaemon(t *testing.T) (*notifytest.FakeDaemon, *dbus.Conn) {
+	t.Helper()
+	daemon, err := notifytest.NewFakeDaemon()
+	if err == notifytest.ErrNoDBusDaemon {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	conn, err := daemon.Connect()
+	if err != nil {
+		_ = daemon.Close()
+	}
+	require.NoError(t, err)
+	return daemon, conn
+}
+
+func TestFakeDaemonSendNotification(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	n := notify.Notification{
+		AppName:       "test",
+		Summary:       "summary",
+		Body:          "body",
+		Actions:       []notify.Action{{Key: "open", Label: "Open"}},
+		ExpireTimeout: 5 * time.Second,
+	}
+	n.SetUrgency(notify.UrgencyCritical)
+
+	id, err := notify.SendNotification(conn, n)
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, n.Summary, sent[0].Summary)
+	require.Equal(t, n.Body, sent[0].Body)
+	require.Equal(t, n.Actions, sent[0].Actions)
+	require.Equal(t, n.ExpireTimeout, sent[0].ExpireTimeout)
+	require.Equal(t, byte(notify.UrgencyCritical), sent[0].Hints["urgency"].Value())
+}
+
+func TestFakeDaemonServerInformation(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	info := notify.ServerInformation{Name: "fake", Vendor: "test", Version: "2.0", SpecVersion: "1.2"}
+	daemon.SetServerInformation(info)
+	daemon.SetCapabilities([]string{"body", "actions"})
+
+	got, err := notify.GetServerInformation(conn)
+	require.NoError(t, err)
+	require.Equal(t, info, got)
+
+	caps, err := notify.GetCapabilities(conn)
+	require.NoError(t, err)
+	require.Equal(t, []string{"body", "actions"}, caps)
+}
+
+func TestFakeDaemonSignals(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	actions := make(chan *notify.ActionInvokedSignal, 1)
+	closed := make(chan *notify.NotificationClosedSignal, 1)
+	notifier, err := notify.New(
+		conn,
+		notify.WithOnAction(func(s *notify.ActionInvokedSignal) { actions <- s }),
+		notify.WithOnClosed(func(s *notify.NotificationClosedSignal) { closed <- s }),
+	)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "signals"})
+	require.NoError(t, err)
+
+	require.NoError(t, daemon.SimulateAction(id, "open"))
+	select {
+	case s := <-actions:
+		require.Equal(t, id, s.ID)
+		require.Equal(t, "open", s.ActionKey)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ActionInvoked")
+	}
+
+	err = notifier.CloseNotification(id)
+	require.NoError(t, err)
+	select {
+	case s := <-closed:
+		require.Equal(t, id, s.ID)
+		require.Equal(t, notify.ReasonClosedByCall, s.Reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for NotificationClosed")
+	}
+}
+
+func TestSendAndWaitForClose(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	go func() {
+		// the fake daemon hands out ids in order, starting from 1
+		time.Sleep(50 * time.Millisecond)
+		_ = daemon.SimulateClose(1, notify.ReasonDismissedByUser)
+	}()
+	s, err := notifier.SendAndWaitForClose(context.Background(), notify.Notification{Summary: "wait"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), s.ID)
+	require.Equal(t, notify.ReasonDismissedByUser, s.Reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s, err = notifier.SendAndWaitForClose(ctx, notify.Notification{Summary: "timeout"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), s.ID)
+	require.Equal(t, notify.ReasonClosedByCall, s.Reason)
+}
+
+func TestNotifierServerCapabilities(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	daemon.SetCapabilities([]string{notify.CapabilityBody, notify.CapabilityBodyMarkup})
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	sc, err := notifier.ServerCapabilities()
+	require.NoError(t, err)
+	require.True(t, sc.SupportsMarkup())
+	require.False(t, sc.SupportsActions())
+	require.Equal(t, "notifytest", sc.ServerInformation.Name)
+}
+
+func TestSendNotificationNilHints(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	id, err := notify.SendNotification(conn, notify.Notification{})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.NotNil(t, sent[0].Hints)
+	require.Empty(t, sent[0].Hints)
+}
+
+func TestSendNotificationUrgencyField(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	n := notify.Notification{Summary: "urgent"}
+	n.SetUrgency(notify.UrgencyLow)
+	n.Urgency = notify.UrgencyCritical.Ptr()
+
+	_, err := notify.SendNotification(conn, n)
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, byte(notify.UrgencyCritical), sent[0].Hints["urgency"].Value())
+	// the caller's hints are untouched
+	require.Equal(t, byte(notify.UrgencyLow), n.Hints["urgency"].Value())
+}
+
+func TestSendNotificationActionIcons(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	n := notify.Notification{
+		Summary: "icons",
+		Actions: []notify.Action{
+			{Key: "reply", Label: "Reply", Icon: "mail-reply"},
+			{Key: "archive", Label: "Archive"},
+		},
+	}
+	_, err := notify.SendNotification(conn, n)
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, []notify.Action{{Key: "mail-reply", Label: "Reply"}, {Key: "archive", Label: "Archive"}}, sent[0].Actions)
+	require.Equal(t, true, sent[0].Hints["action-icons"].Value())
+	// the caller's hints are untouched
+	require.Nil(t, n.Hints)
+
+	s := &notify.ActionInvokedSignal{ActionKey: "mail-reply"}
+	require.True(t, s.MatchesAction(n.Actions[0]))
+	require.False(t, s.MatchesAction(n.Actions[1]))
+}
+
+func TestNotifierStats(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	closed := make(chan *notify.NotificationClosedSignal, 1)
+	notifier, err := notify.New(conn, notify.WithOnClosed(func(s *notify.NotificationClosedSignal) { closed <- s }))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "stats"})
+	require.NoError(t, err)
+	require.NoError(t, daemon.SimulateAction(id, "open"))
+	err = notifier.CloseNotification(id)
+	require.NoError(t, err)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for NotificationClosed")
+	}
+
+	stats := notifier.Stats()
+	require.EqualValues(t, 1, stats.SendCount)
+	require.EqualValues(t, 0, stats.SendErrorCount)
+	require.EqualValues(t, 1, stats.CloseCount)
+	require.EqualValues(t, 1, stats.ActionSignalsReceived)
+	require.EqualValues(t, 1, stats.ClosedSignalsReceived)
+}
+
+// useSessionBus points the session bus address at the bus of daemon, until the returned func is called.
+func useSessionBus(daemon *notifytest.FakeDaemon) func() {
+	old, ok := os.LookupEnv("DBUS_SESSION_BUS_ADDRESS")
+	_ = os.Setenv("DBUS_SESSION_BUS_ADDRESS", daemon.Address())
+	return func() {
+		if ok {
+			_ = os.Setenv("DBUS_SESSION_BUS_ADDRESS", old)
+		} else {
+			_ = os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+		}
+	}
+}
+
+func TestNewSessionBusNotifierPrivate(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	defer useSessionBus(daemon)()
+
+	notifier, err := notify.NewSessionBusNotifier(notify.WithSessionBusPrivate())
+	require.NoError(t, err)
+	require.True(t, notify.IsConnectionOwned(notifier))
+	// wrappers are seen through
+	scope, cancel := notify.NewNotificationScope(context.Background(), notifier)
+	defer cancel()
+	_, recording := notifytest.NewObserverNotifier(notify.CapabilityFilterMiddleware(nil)(notifier))
+	for _, wrapper := range []notify.Notifier{
+		scope,
+		notify.NewCoalescingNotifier(notifier, time.Second, nil),
+		notify.NewGroupedNotifier(notifier, nil, 0),
+		notify.NewNotifierRouter(nil, notifier),
+		notify.NewNotifierRouter(nil, notify.NewGroupedNotifier(recording, nil, 0)),
+	} {
+		require.True(t, notify.IsConnectionOwned(wrapper), "%T", wrapper)
+	}
+	require.NotNil(t, notifier.Conn())
+	require.False(t, conn == notifier.Conn())
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "private"})
+	require.NoError(t, err)
+	require.Len(t, daemon.SentNotifications(), 1)
+
+	require.NoError(t, notifier.Close())
+	// the private connection is closed together with the notifier
+	_, err = notifier.SendNotification(notify.Notification{Summary: "closed"})
+	require.Error(t, err)
+}
+
+func TestNewSessionBusNotifierDialTimeout(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	defer useSessionBus(daemon)()
+
+	notifier, err := notify.NewSessionBusNotifier(notify.WithSessionBusPrivate(), notify.WithDialTimeout(5*time.Second))
+	require.NoError(t, err)
+	defer notifier.Close()
+	_, err = notifier.SendNotification(notify.Notification{Summary: "connected in time"})
+	require.NoError(t, err)
+
+	_, err = notify.NewSessionBusNotifier(notify.WithSessionBusPrivate(), notify.WithDialTimeout(-time.Second))
+	require.Error(t, err)
+}
+
+func TestCloseNotificationNotFound(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	err = notifier.CloseNotification(1234)
+	require.True(t, notify.IsNotFound(err), "got: %v", err)
+}
+
+func TestNotifierIsOpen(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	require.True(t, notifier.IsOpen())
+	id, err := notifier.SendNotification(notify.Notification{Summary: "open"})
+	require.NoError(t, err)
+
+	require.NoError(t, notifier.Close())
+	require.False(t, notifier.IsOpen())
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "closed"})
+	require.Equal(t, notify.ErrNotifierClosed, err)
+	require.Equal(t, notify.ErrNotifierClosed, notifier.CloseNotification(id))
+	require.Len(t, daemon.SentNotifications(), 1)
+}
+
+func TestCloseNotificationSync(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	var closedCount int32
+	notifier, err := notify.New(conn, notify.WithOnClosed(func(s *notify.NotificationClosedSignal) {
+		atomic.AddInt32(&closedCount, 1)
+	}))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "close me"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := notifier.CloseNotificationSync(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, id, s.ID)
+	require.Equal(t, notify.ReasonClosedByCall, s.Reason)
+	require.EqualValues(t, 1, atomic.LoadInt32(&closedCount))
+
+	_, err = notifier.CloseNotificationSync(ctx, id)
+	require.True(t, notify.IsNotFound(err))
+}
+
+func TestGetDefaultSession(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	defer useSessionBus(daemon)()
+
+	session, err := notify.GetDefaultSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	info, err := notify.GetServerInformation(session)
+	require.NoError(t, err)
+	require.Equal(t, "notifytest", info.Name)
+}
+
+func TestConnectAndAuthenticate(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	defer useSessionBus(daemon)()
+
+	session, err := notify.ConnectAndAuthenticate(
+		notify.WithAuthMethods([]dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}),
+		notify.WithConnectTimeout(5*time.Second),
+	)
+	require.NoError(t, err)
+	defer session.Close()
+	_, err = notify.GetServerInformation(session)
+	require.NoError(t, err)
+
+	_, err = notify.ConnectAndAuthenticate(notify.WithSocketPath(filepath.Join(os.TempDir(), "notify-does-not-exist")))
+	require.Error(t, err)
+	_, err = notify.ConnectAndAuthenticate(notify.WithConnectTimeout(-time.Second))
+	require.Error(t, err)
+}
+
+func TestNotificationScope(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	base, err := notify.New(conn)
+	require.NoError(t, err)
+	defer base.Close()
+
+	ctx, cancelParent := context.WithCancel(context.Background())
+	scope, cancel := notify.NewNotificationScope(ctx, base)
+	defer cancel()
+
+	first, err := scope.SendNotification(notify.Notification{Summary: "first"})
+	require.NoError(t, err)
+	second, err := scope.SendNotification(notify.Notification{Summary: "second"})
+	require.NoError(t, err)
+	// notifications sent directly on base are not tracked by the scope
+	outside, err := base.SendNotification(notify.Notification{Summary: "outside"})
+	require.NoError(t, err)
+
+	cancelParent()
+	select {
+	case <-scope.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("scope was not cleaned up")
+	}
+
+	for _, id := range []uint32{first, second} {
+		err = base.CloseNotification(id)
+		require.True(t, notify.IsNotFound(err))
+	}
+	err = base.CloseNotification(outside)
+	require.NoError(t, err)
+
+	_, err = scope.SendNotification(notify.Notification{Summary: "closed"})
+	require.Equal(t, notify.ErrScopeClosed, err)
+}
+
+func TestUpdateProgress(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	original, err := notify.NewProgressNotification("app", "Downloading", 0)
+	require.NoError(t, err)
+	id, err := notifier.SendNotification(original)
+	require.NoError(t, err)
+
+	updatedID, err := notify.UpdateProgress(context.Background(), notifier, id, original, 50)
+	require.NoError(t, err)
+	require.NotZero(t, updatedID)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 2)
+	require.Equal(t, id, sent[1].ReplacesID)
+	require.Equal(t, "50%", sent[1].Body)
+	require.Equal(t, int32(50), sent[1].Hints["value"].Value())
+	// the original is left untouched
+	require.Equal(t, "0%", original.Body)
+	require.Equal(t, int32(0), original.Hints["value"].Value())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = notify.UpdateProgress(ctx, notifier, updatedID, original, 60)
+	require.Equal(t, context.Canceled, err)
+	require.Len(t, daemon.SentNotifications(), 2)
+}
+
+func TestAutoFilteringNotifier(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	daemon.SetCapabilities([]string{notify.CapabilityBody})
+
+	base, err := notify.New(conn)
+	require.NoError(t, err)
+	defer base.Close()
+
+	notifier, err := notify.AutoFilteringNotifier(base)
+	require.NoError(t, err)
+
+	n := notify.Notification{Summary: "summary", Body: "body", Actions: []notify.Action{{Key: "open", Label: "Open"}}}
+	n.AddHint(notify.HintSoundWithName("bell"))
+	_, err = notifier.SendNotification(n)
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, "body", sent[0].Body)
+	require.Empty(t, sent[0].Actions)
+	require.NotContains(t, sent[0].Hints, "sound-name")
+}
+
+func TestGetCapabilitiesUnsupported(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	daemon.SetCapabilitiesUnsupported(true)
+
+	logger := &recordingLogger{}
+	base, err := notify.New(conn, notify.WithLogger(logger), notify.WithAutoEscapeBody())
+	require.NoError(t, err)
+	defer base.Close()
+
+	_, err = base.GetCapabilities()
+	require.True(t, notify.IsCapabilitiesUnsupported(err), "got %v", err)
+	_, err = base.ServerCapabilities()
+	require.True(t, notify.IsCapabilitiesUnsupported(err), "got %v", err)
+
+	// no body-markup capability, so markup is stripped
+	_, err = base.SendNotification(notify.Notification{Summary: "summary", Body: "<b>body</b>"})
+	require.NoError(t, err)
+	require.Equal(t, 1, logger.count("assuming no optional capabilities"))
+
+	notifier, err := notify.AutoFilteringNotifier(base)
+	require.NoError(t, err)
+	n := notify.Notification{Summary: "summary", Body: "body", Actions: []notify.Action{{Key: "open", Label: "Open"}}}
+	_, err = notifier.SendNotification(n)
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 2)
+	require.Equal(t, "body", sent[0].Body)
+	require.Empty(t, sent[1].Body)
+	require.Empty(t, sent[1].Actions)
+}
+
+func TestSendNotificationWithHints(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	n := notify.Notification{Summary: "hints"}
+	n.SetUrgency(notify.UrgencyLow)
+
+	_, err := notify.SendNotificationWithHints(conn, n, notify.HintUrgency(notify.UrgencyCritical), notify.HintSoundWithName("bell"))
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, byte(notify.UrgencyCritical), sent[0].Hints["urgency"].Value())
+	require.Equal(t, "bell", sent[0].Hints["sound-name"].Value())
+	// the caller's notification is untouched
+	require.Equal(t, byte(notify.UrgencyLow), n.Hints["urgency"].Value())
+	require.Len(t, n.Hints, 1)
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) count(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := 0
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestWithAutoEscapeBody(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	daemon.SetCapabilities([]string{notify.CapabilityBody})
+
+	log := &recordingLogger{}
+	notifier, err := notify.New(conn, notify.WithAutoEscapeBody(), notify.WithLogger(log))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = notifier.SendNotification(notify.Notification{Summary: "markup", Body: "<b>bold</b> &amp; plain"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, notifier.Close())
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 2)
+	require.Equal(t, "bold & plain", sent[0].Body)
+	require.Equal(t, "bold & plain", sent[1].Body)
+
+	require.Equal(t, 1, log.count("stripping markup"))
+}
+
+func TestWithDefaultAppName(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithDefaultAppName("default-app"))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "default"})
+	require.NoError(t, err)
+	_, err = notifier.SendNotification(notify.Notification{AppName: "explicit", Summary: "explicit"})
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 2)
+	require.Equal(t, "default-app", sent[0].AppName)
+	require.Equal(t, "explicit", sent[1].AppName)
+
+	require.Equal(t, filepath.Base(os.Args[0]), notify.DefaultAppNameFromBinary())
+}
+
+func TestWaitForSignal(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	first, err := notifier.SendNotification(notify.Notification{Summary: "first"})
+	require.NoError(t, err)
+	second, err := notifier.SendNotification(notify.Notification{Summary: "second"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan notify.NotificationEvent, 2)
+	errs := make(chan error, 2)
+	for _, id := range []uint32{first, second} {
+		go func(id uint32) {
+			e, err := notifier.WaitForSignal(ctx, id)
+			errs <- err
+			events <- e
+		}(id)
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, daemon.SimulateAction(second, "open"))
+	require.NoError(t, daemon.SimulateClose(first, notify.ReasonExpired))
+
+	got := map[uint32]notify.NotificationEvent{}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-errs)
+		e := <-events
+		got[e.ID] = e
+	}
+	require.True(t, got[first].IsClosed())
+	require.Equal(t, notify.ReasonExpired, got[first].Closed.Reason)
+	require.True(t, got[second].IsAction())
+	require.Equal(t, "open", got[second].Action.ActionKey)
+
+	// a recently closed notification resolves right away
+	e, err := notifier.WaitForSignal(ctx, first)
+	require.NoError(t, err)
+	require.True(t, e.IsClosed())
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	_, err = notifier.WaitForSignal(short, second)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestChain(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	// resolve every notification as soon as it shows up at the daemon
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		resolved := 0
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			sent := daemon.SentNotifications()
+			for ; resolved < len(sent) && resolved < 2; resolved++ {
+				// ids are handed out in order, starting from 1
+				time.Sleep(20 * time.Millisecond)
+				_ = daemon.SimulateAction(uint32(resolved+1), "next")
+			}
+		}
+	}()
+
+	notes := []notify.Notification{{Summary: "step 1"}, {Summary: "step 2"}, {Summary: "step 3"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	events, err := notify.Chain(ctx, notifier, notes)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Len(t, events, 3)
+	require.True(t, events[0].IsAction())
+	require.True(t, events[1].IsAction())
+	require.Equal(t, notify.NotificationEvent{}, events[2])
+	require.Len(t, daemon.SentNotifications(), 3)
+}
+
+func TestSignalWaiter(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	waiter := notify.NewSignalWaiter(notifier)
+	first, err := waiter.Add(notify.Notification{Summary: "first"})
+	require.NoError(t, err)
+	second, err := waiter.Add(notify.Notification{Summary: "second"})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		// an action followed by a close counts once
+		_ = daemon.SimulateAction(first, "open")
+		_ = daemon.SimulateClose(first, notify.ReasonDismissedByUser)
+		_ = daemon.SimulateClose(second, notify.ReasonExpired)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, waiter.Wait(ctx))
+
+	// a notification without signals makes Wait time out
+	_, err = waiter.Add(notify.Notification{Summary: "never resolved"})
+	require.NoError(t, err)
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	require.Equal(t, context.DeadlineExceeded, waiter.Wait(short))
+}
+
+func TestSignalWaiterAndChainInstantAction(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	// the action may be received before sending returns, and the notification stays open
+	daemon.SetInstantAction("open")
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waiter := notify.NewSignalWaiter(notifier)
+	for i := 0; i < 10; i++ {
+		_, err := waiter.Add(notify.Notification{Summary: "instant " + strconv.Itoa(i)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, waiter.Wait(ctx))
+
+	events, err := notify.Chain(ctx, notifier, []notify.Notification{{Summary: "step 1"}, {Summary: "step 2"}})
+	require.NoError(t, err)
+	for _, e := range events {
+		require.True(t, e.IsAction())
+	}
+}
+
+func TestShutdownTimesOutOnBlockedHandler(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	notifier, err := notify.New(conn, notify.WithOnAction(func(s *notify.ActionInvokedSignal) {
+		close(entered)
+		<-release
+	}))
+	require.NoError(t, err)
+	defer close(release)
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "blocked"})
+	require.NoError(t, err)
+	require.NoError(t, daemon.SimulateAction(id, "open"))
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, notifier.Shutdown(ctx))
+	// later calls return the same result without blocking
+	require.Equal(t, context.DeadlineExceeded, notifier.Close())
+}
+
+func TestShutdown(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, notifier.Shutdown(ctx))
+	require.NoError(t, notifier.Close())
+}
+
+func TestWithDefaultAppIcon(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithDefaultAppIcon("mail-unread"))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "default"})
+	require.NoError(t, err)
+	_, err = notifier.SendNotification(notify.Notification{AppIcon: "dialog-error", Summary: "explicit"})
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 2)
+	require.Equal(t, "mail-unread", sent[0].AppIcon)
+	require.Equal(t, "dialog-error", sent[1].AppIcon)
+}
+
+func TestServerNotRunning(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithDestination("org.example.NotRunning"))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "nobody listens"})
+	require.True(t, notify.IsServerNotRunning(err), err)
+	_, err = notifier.GetCapabilities()
+	require.True(t, notify.IsServerNotRunning(err), err)
+	_, err = notifier.GetServerInformation()
+	require.True(t, notify.IsServerNotRunning(err), err)
+}
+
+func TestNotifierClone(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	closedOriginal := make(chan *notify.NotificationClosedSignal, 1)
+	original, err := notify.New(conn,
+		notify.WithDefaultAppName("original"),
+		notify.WithDefaultAppIcon("dialog-information"),
+		notify.WithOnClosed(func(s *notify.NotificationClosedSignal) { closedOriginal <- s }),
+	)
+	require.NoError(t, err)
+	defer original.Close()
+
+	closedClone := make(chan *notify.NotificationClosedSignal, 1)
+	clone, err := original.Clone(
+		notify.WithDefaultAppName("clone"),
+		notify.WithOnClosed(func(s *notify.NotificationClosedSignal) { closedClone <- s }),
+	)
+	require.NoError(t, err)
+
+	id, err := clone.SendNotification(notify.Notification{Summary: "from clone"})
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 1)
+	require.Equal(t, "clone", sent[0].AppName)
+	require.Equal(t, "dialog-information", sent[0].AppIcon)
+
+	require.NoError(t, daemon.SimulateClose(id, notify.ReasonDismissedByUser))
+	for _, ch := range []chan *notify.NotificationClosedSignal{closedOriginal, closedClone} {
+		select {
+		case s := <-ch:
+			require.Equal(t, id, s.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for closed signal")
+		}
+	}
+
+	// closing the clone leaves the original running
+	require.NoError(t, clone.Close())
+	id, err = original.SendNotification(notify.Notification{Summary: "from original"})
+	require.NoError(t, err)
+	require.Equal(t, "original", daemon.SentNotifications()[1].AppName)
+
+	require.NoError(t, daemon.SimulateClose(id, notify.ReasonExpired))
+	select {
+	case s := <-closedOriginal:
+		require.Equal(t, id, s.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for closed signal")
+	}
+}
+
+func TestWithListenerTTL(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithListenerTTL(100*time.Millisecond), notify.WithLogger(&recordingLogger{}))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "never closed"})
+	require.NoError(t, err)
+
+	event, err := notifier.WaitForSignal(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, event.IsClosed())
+	require.Equal(t, notify.ReasonUnknown, event.Closed.Reason)
+
+	_, err = notify.New(conn, notify.WithListenerTTL(-time.Second))
+	require.Error(t, err)
+}
+
+func TestWithCacheServerInformation(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithCacheServerInformation(0))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	info, err := notifier.GetServerInformation()
+	require.NoError(t, err)
+	require.Equal(t, "notifytest", info.Name)
+
+	daemon.SetServerInformation(notify.ServerInformation{Name: "restarted", Vendor: "test", Version: "2", SpecVersion: "1.2"})
+	info, err = notifier.GetServerInformation()
+	require.NoError(t, err)
+	require.Equal(t, "notifytest", info.Name)
+
+	notifier.FlushCache()
+	info, err = notifier.GetServerInformation()
+	require.NoError(t, err)
+	require.Equal(t, "restarted", info.Name)
+}
+
+func TestWithContext(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifier, err := notify.New(conn, notify.WithContext(ctx))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "before cancel"})
+	require.NoError(t, err)
+
+	cancel()
+	_, err = notifier.SendNotification(notify.Notification{Summary: "after cancel"})
+	require.True(t, errors.Is(err, context.Canceled), "%v", err)
+	_, err = notifier.GetCapabilities()
+	require.True(t, errors.Is(err, context.Canceled), "%v", err)
+
+	// a context passed to the call takes priority
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = daemon.SimulateClose(2, notify.ReasonExpired)
+	}()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	s, err := notifier.SendAndWaitForClose(waitCtx, notify.Notification{Summary: "own context"})
+	require.NoError(t, err)
+	require.Equal(t, notify.ReasonExpired, s.Reason)
+	require.Len(t, daemon.SentNotifications(), 2)
+}
+
+type kdeServer struct {
+	summaries chan string
+}
+
+func (s *kdeServer) Notify(appName string, replacesID uint32, appIcon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.summaries <- summary
+	return 42, nil
+}
+
+func TestKDENotifier(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	// without org.kde.Notifications, the standard interface is used
+	notifier, err := notify.KDENotifier(conn)
+	require.NoError(t, err)
+	_, err = notifier.SendNotification(notify.Notification{Summary: "standard"})
+	require.NoError(t, err)
+	require.NoError(t, notifier.Close())
+	require.Len(t, daemon.SentNotifications(), 1)
+
+	kdeConn, err := daemon.Connect()
+	require.NoError(t, err)
+	defer kdeConn.Close()
+	kde := &kdeServer{summaries: make(chan string, 1)}
+	require.NoError(t, kdeConn.Export(kde, "/org/kde/Notifications", "org.kde.Notifications"))
+	reply, err := kdeConn.RequestName("org.kde.Notifications", dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+
+	notifier, err = notify.KDENotifier(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+	id, err := notifier.SendNotification(notify.Notification{Summary: "kde"})
+	require.NoError(t, err)
+	require.EqualValues(t, 42, id)
+	require.Equal(t, "kde", <-kde.summaries)
+	require.Len(t, daemon.SentNotifications(), 1)
+}
+
+func TestDiscoverNotificationServices(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	kdeConn, err := daemon.Connect()
+	require.NoError(t, err)
+	defer kdeConn.Close()
+	require.NoError(t, kdeConn.Export(&kdeServer{}, "/org/kde/Notifications", "org.kde.Notifications"))
+	for _, name := range []string{"org.kde.Notifications", "org.example.NotificationsNot"} {
+		reply, err := kdeConn.RequestName(name, dbus.NameFlagDoNotQueue)
+		require.NoError(t, err)
+		require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+	}
+
+	names, err := notify.DiscoverNotificationServices(conn)
+	require.NoError(t, err)
+	require.Equal(t, []string{"org.freedesktop.Notifications", "org.kde.Notifications"}, names)
+
+	services, err := notify.DescribeNotificationServices(context.Background(), conn)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+	require.Equal(t, "org.freedesktop.Notifications", services[0].Name)
+	require.NoError(t, services[0].Err)
+	require.Equal(t, "notifytest", services[0].Info.Name)
+	// kdeServer does not implement GetServerInformation
+	require.Equal(t, "org.kde.Notifications", services[1].Name)
+	require.Error(t, services[1].Err)
+	require.True(t, services[1].Info.IsUnknown())
+}
+
+func TestScheduledNotificationRefresh(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	tmpl, err := notify.NewNotificationTemplate(notifier, notify.Notification{Summary: "Counter", Body: "count: {{.}}"})
+	require.NoError(t, err)
+	var count int32
+	scheduled := tmpl.BindData(func() interface{} {
+		return atomic.AddInt32(&count, 1)
+	})
+
+	const period = 20 * time.Millisecond
+	stop, err := scheduled.Refresh(period)
+	require.NoError(t, err)
+	defer stop()
+
+	require.Eventually(t, func() bool { return len(daemon.SentNotifications()) >= 3 }, 50*period, period/2)
+	sent := daemon.SentNotifications()
+	require.Equal(t, "count: 1", sent[0].Body)
+	require.Equal(t, "count: 2", sent[1].Body)
+	require.Zero(t, sent[0].ReplacesID)
+	require.Equal(t, scheduled.ID(), sent[1].ReplacesID)
+
+	// refreshing stops once the notification is dismissed
+	require.NoError(t, daemon.SimulateClose(scheduled.ID(), notify.ReasonDismissedByUser))
+	require.Eventually(t, func() bool {
+		before := len(daemon.SentNotifications())
+		time.Sleep(3 * period)
+		return len(daemon.SentNotifications()) == before
+	}, 50*period, period)
+	stop()
+	require.NoError(t, scheduled.Err())
+}
+
+type portalServer struct {
+	added   chan map[string]dbus.Variant
+	removed chan string
+}
+
+func (s *portalServer) AddNotification(id string, n map[string]dbus.Variant) *dbus.Error {
+	n["id"] = dbus.MakeVariant(id)
+	s.added <- n
+	return nil
+}
+
+func (s *portalServer) RemoveNotification(id string) *dbus.Error {
+	s.removed <- id
+	return nil
+}
+
+func TestPortalNotifier(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	portalConn, err := daemon.Connect()
+	require.NoError(t, err)
+	defer portalConn.Close()
+	portal := &portalServer{added: make(chan map[string]dbus.Variant, 1), removed: make(chan string, 1)}
+	require.NoError(t, portalConn.Export(portal, "/org/freedesktop/portal/desktop", "org.freedesktop.portal.Notification"))
+	reply, err := portalConn.RequestName("org.freedesktop.portal.Desktop", dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+
+	actions := make(chan *notify.ActionInvokedSignal, 1)
+	notifier, err := notify.PortalNotifier(conn, notify.WithActionChannel(actions))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	_, err = notifier.GetCapabilities()
+	require.True(t, notify.IsCapabilitiesUnsupported(err), "got %v", err)
+
+	id, err := notifier.SendNotification(notify.Notification{
+		Summary: "portal",
+		Actions: []notify.Action{{Key: "reply", Label: "Reply"}},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+	added := <-portal.added
+	require.Equal(t, strconv.Itoa(int(id)), added["id"].Value())
+	require.Equal(t, "portal", added["title"].Value())
+	require.Empty(t, daemon.SentNotifications())
+
+	// replacing keeps the ID
+	replaced, err := notifier.SendNotification(notify.Notification{Summary: "replaced", ReplacesID: id})
+	require.NoError(t, err)
+	require.Equal(t, id, replaced)
+	require.Equal(t, strconv.Itoa(int(id)), (<-portal.added)["id"].Value())
+
+	require.NoError(t, portalConn.Emit("/org/freedesktop/portal/desktop", "org.freedesktop.portal.Notification.ActionInvoked",
+		strconv.Itoa(int(id)), "reply", []dbus.Variant{}))
+	select {
+	case s := <-actions:
+		require.Equal(t, id, s.ID)
+		require.Equal(t, "reply", s.ActionKey)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ActionInvoked")
+	}
+
+	require.NoError(t, notifier.CloseNotification(id))
+	require.Equal(t, strconv.Itoa(int(id)), <-portal.removed)
+}
+
+func TestSendWithID(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+	// the signal is emitted before the server replies, so it may be received before SendWithID returns
+	daemon.SetInstantAction("open")
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+	reserver := notifier.(notify.IDReserver)
+
+	_, err = reserver.SendWithID(1, notify.Notification{Summary: "not reserved"})
+	require.Equal(t, notify.ErrIDNotReserved, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		reserved := reserver.ReserveID()
+		events := make(chan notify.NotificationEvent, 1)
+		go func() {
+			e, _ := notifier.WaitForSignal(ctx, reserved)
+			events <- e
+		}()
+		// give the listener time to register
+		time.Sleep(10 * time.Millisecond)
+
+		id, err := reserver.SendWithID(reserved, notify.Notification{Summary: "instant " + strconv.Itoa(i)})
+		require.NoError(t, err)
+		require.NotEqual(t, reserved, id)
+		e := <-events
+		require.Equal(t, id, e.ID)
+		require.True(t, e.IsAction())
+		require.Equal(t, "open", e.Action.ActionKey)
+
+		_, err = reserver.SendWithID(reserved, notify.Notification{Summary: "again"})
+		require.Equal(t, notify.ErrIDNotReserved, err)
+	}
+}
+
+func TestCloseFromHandler(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	var notifier notify.Notifier
+	closed := make(chan error, 1)
+	notifier, err := notify.New(conn, notify.WithOnClosed(func(*notify.NotificationClosedSignal) {
+		closed <- notifier.Close()
+	}))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "close from handler"})
+	require.NoError(t, err)
+	require.NoError(t, daemon.SimulateClose(id, notify.ReasonDismissedByUser))
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close from a handler did not return")
+	}
+	require.False(t, notifier.IsOpen())
+}
+
+func TestPackageCloseNotification(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	id, err := notify.SendNotification(conn, notify.Notification{Summary: "close me"})
+	require.NoError(t, err)
+
+	require.NoError(t, notify.CloseNotification(conn, id))
+	err = notify.CloseNotificationContext(context.Background(), conn, id)
+	require.True(t, notify.IsNotFound(err), "got: %v", err)
+	require.Equal(t, notify.ErrInvalidNotificationID, notify.CloseNotification(conn, 0))
+}
+
+func TestWithBodySplitting(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithBodySplitting(12))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	ids, err := notifier.SendNotificationSplit(notify.Notification{Summary: "log", Body: "First one. Second one. Third."})
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 3)
+	require.Equal(t, "log (1/3)", sent[0].Summary)
+	require.Equal(t, "First one.", sent[0].Body)
+	require.Equal(t, "log (3/3)", sent[2].Summary)
+	require.Equal(t, "Third.", sent[2].Body)
+
+	id, err := notifier.SendNotification(notify.Notification{Summary: "short", Body: "fits"})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+	require.Equal(t, "short", daemon.SentNotifications()[3].Summary)
+}
+
+func TestPackageContextCalls(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	caps, err := notify.GetCapabilitiesContext(context.Background(), conn)
+	require.NoError(t, err)
+	require.Contains(t, caps, notify.CapabilityActions)
+
+	info, err := notify.GetServerInformationContext(context.Background(), conn)
+	require.NoError(t, err)
+	require.Equal(t, "notifytest", info.Name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = notify.GetCapabilitiesContext(ctx, conn)
+	require.True(t, errors.Is(err, context.Canceled), "%v", err)
+	_, err = notify.GetServerInformationContext(ctx, conn)
+	require.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
+func TestNotifierConn(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	require.True(t, conn == notifier.Conn())
+	require.False(t, notify.IsConnectionOwned(notifier))
+	require.False(t, notify.IsConnectionOwned(notify.CapabilityFilterMiddleware(nil)(notifier)))
+}
+
+func TestWithIconAutoResolve(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	dataHome, err := ioutil.TempDir("", "notify-data-home")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataHome)
+	old, ok := os.LookupEnv("XDG_DATA_HOME")
+	defer func() {
+		if ok {
+			_ = os.Setenv("XDG_DATA_HOME", old)
+		} else {
+			_ = os.Unsetenv("XDG_DATA_HOME")
+		}
+	}()
+	_ = os.Setenv("XDG_DATA_HOME", dataHome)
+	require.NoError(t, os.MkdirAll(filepath.Join(dataHome, "applications"), 0755))
+	entry := filepath.Join(dataHome, "applications", "org.example.Running.desktop")
+	require.NoError(t, ioutil.WriteFile(entry, []byte("[Desktop Entry]\nIcon=running-icon\n"), 0644))
+
+	// the application is running when its bus name is owned
+	reply, err := conn.RequestName("org.example.Running", dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+
+	notifier, err := notify.New(conn, notify.WithIconAutoResolve(), notify.WithDefaultAppIcon("default-icon"))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	for _, n := range []notify.Notification{
+		{AppName: "org.example.Running", Summary: "resolved"},
+		{AppName: "org.example.Stopped", Summary: "not running"},
+		{AppName: "org.example.Running", AppIcon: "explicit", Summary: "explicit"},
+	} {
+		_, err = notifier.SendNotification(n)
+		require.NoError(t, err)
+	}
+
+	sent := daemon.SentNotifications()
+	require.Equal(t, "running-icon", sent[0].AppIcon)
+	require.Equal(t, "default-icon", sent[1].AppIcon)
+	require.Equal(t, "explicit", sent[2].AppIcon)
+}
+
+func TestCoalescingNotifier(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	base, err := notify.New(conn)
+	require.NoError(t, err)
+	defer base.Close()
+
+	window := 100 * time.Millisecond
+	coalescing := notify.NewCoalescingNotifier(base, window, func(n notify.Notification) string { return n.AppName })
+	defer coalescing.Close()
+
+	first, err := coalescing.SendNotification(notify.Notification{AppName: "copy", Summary: "10%"})
+	require.NoError(t, err)
+	for _, progress := range []string{"20%", "30%", "40%"} {
+		id, err := coalescing.SendNotification(notify.Notification{AppName: "copy", Summary: progress})
+		require.NoError(t, err)
+		require.Equal(t, first, id)
+	}
+	// notifications without a key are not held
+	_, err = coalescing.SendNotification(notify.Notification{Summary: "other"})
+	require.NoError(t, err)
+	require.Len(t, daemon.SentNotifications(), 2)
+
+	// only the most recent update is sent when the window ends, replacing the first
+	require.Eventually(t, func() bool { return len(daemon.SentNotifications()) == 3 }, 5*window, window/10)
+	last := daemon.SentNotifications()[2]
+	require.Equal(t, "40%", last.Summary)
+	require.Equal(t, first, last.ReplacesID)
+
+	// an update held on Close is sent right away
+	_, err = coalescing.SendNotification(notify.Notification{AppName: "copy", Summary: "100%"})
+	require.NoError(t, err)
+	require.NoError(t, coalescing.Close())
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 4)
+	require.Equal(t, "100%", sent[3].Summary)
+}
+
+func TestGroupedNotifier(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	base, err := notify.New(conn)
+	require.NoError(t, err)
+	defer base.Close()
+
+	grouped := notify.NewGroupedNotifier(base, func(n notify.Notification) string { return n.AppName }, 0)
+	defer grouped.Close()
+
+	first, err := grouped.SendNotification(notify.Notification{AppName: "build", Summary: "Build failed", Body: "error A"})
+	require.NoError(t, err)
+	second, err := grouped.SendNotification(notify.Notification{AppName: "build", Summary: "Build failed", Body: "error B"})
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	other, err := grouped.SendNotification(notify.Notification{AppName: "deploy", Summary: "Deployed"})
+	require.NoError(t, err)
+	require.NotEqual(t, first, other)
+
+	// without a body, the summary is used, also for the first notification of a group
+	_, err = grouped.SendNotification(notify.Notification{AppName: "deploy", Summary: "Rolled back"})
+	require.NoError(t, err)
+
+	sent := daemon.SentNotifications()
+	require.Len(t, sent, 4)
+	require.Equal(t, first, sent[1].ReplacesID)
+	require.Equal(t, "error A\nerror B", sent[1].Body)
+	require.Zero(t, sent[2].ReplacesID)
+	require.Equal(t, "Deployed", sent[2].Body)
+	require.Equal(t, other, sent[3].ReplacesID)
+	require.Equal(t, "Deployed\nRolled back", sent[3].Body)
+
+	// once closed, the group starts over
+	_, err = base.CloseNotificationSync(context.Background(), first)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		id, err := grouped.SendNotification(notify.Notification{AppName: "build", Summary: "Build failed", Body: "error C"})
+		require.NoError(t, err)
+		return id != first
+	}, 5*time.Second, 10*time.Millisecond)
+	sent = daemon.SentNotifications()
+	require.Zero(t, sent[len(sent)-1].ReplacesID)
+	require.Equal(t, "error C", sent[len(sent)-1].Body)
+}
+
+func TestWithMaxNotificationSize(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	notifier, err := notify.New(conn, notify.WithMaxNotificationSize(1024))
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	n := notify.Notification{Summary: "large", Body: strings.Repeat("x", 2048)}
+	_, err = notifier.SendNotification(n)
+	var tooLarge *notify.NotificationTooLargeError
+	require.True(t, errors.As(err, &tooLarge), "%v", err)
+	require.Equal(t, 1024, tooLarge.Max)
+	require.Greater(t, tooLarge.Estimated, 2048)
+	require.Empty(t, daemon.SentNotifications())
+
+	_, err = notifier.SendNotification(notify.TrimToSize(n, 1024))
+	require.NoError(t, err)
+	require.Len(t, daemon.SentNotifications(), 1)
+}
+
+func TestWithOnDaemonRestart(t *testing.T) {
+	daemon, conn := startFakeDaemon(t)
+	defer daemon.Close()
+	defer conn.Close()
+
+	restarted := make(chan struct{}, 2)
+	notifier, err := notify.New(conn,
+		notify.WithLogger(&recordingLogger{}),
+		notify.WithCacheServerInformation(0),
+		notify.WithOnDaemonRestart(func() { restarted <- struct{}{} }),
+	)
+	require.NoError(t, err)
+	defer notifier.Close()
+
+	info, err := notifier.GetServerInformation()
+	require.NoError(t, err)
+	require.Equal(t, "notifytest", info.Name)
+	daemon.SetServerInformation(notify.ServerInformation{Name: "restarted"})
+
+	require.NoError(t, daemon.SimulateRestart())
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for restart")
+	}
+	select {
+	case <-restarted:
+		t.Fatal("restart handler called twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the cache was flushed
+	info, err = notifier.GetServerInformation()
+	require.NoError(t, err)
+	require.Equal(t, "restarted", info.Name)
+
+	_, err = notifier.SendNotification(notify.Notification{Summary: "after restart"})
+	require.NoError(t, err)
+}
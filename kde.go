@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// The legacy KDE notification service, with the same methods and signals as org.freedesktop.Notifications.
+const (
+	kdeDestination = "org.kde.Notifications"
+	kdeInterface   = "org.kde.Notifications"
+	kdeObjectPath  = dbus.ObjectPath("/org/kde/Notifications")
+)
+
+// actionTooltipHintPrefix is prefixed to the action key to form the ID of an action tooltip hint.
+const actionTooltipHintPrefix = "x-kde-action-tooltip-"
+
+// HintActionTooltip sets a tooltip for the action with key.
+// Tooltips are not defined by the spec, and are only shown by KDE.
+//
+// IsKDEExtension: true
+func HintActionTooltip(key, tooltip string) Hint {
+	return Hint{
+		ID:      actionTooltipHintPrefix + key,
+		Variant: dbus.MakeVariant(tooltip),
+	}
+}
+
+// WithActionTooltips sets tooltips, keyed by action key, for the actions of all notifications sent,
+// using HintActionTooltip. A tooltip hint already set on a notification takes precedence.
+//
+// IsKDEExtension: true
+func WithActionTooltips(tooltips map[string]string) option {
+	return func(n *notifier) {
+		n.actionTooltips = tooltips
+	}
+}
+
+// addActionTooltips adds tooltips for the actions of note, leaving tooltips already set alone.
+func addActionTooltips(note Notification, tooltips map[string]string) Notification {
+	for _, a := range note.Actions {
+		tooltip, ok := tooltips[a.Key]
+		if !ok {
+			continue
+		}
+		h := HintActionTooltip(a.Key, tooltip)
+		if _, exists := note.Hints[h.ID]; !exists {
+			note.AddHint(h)
+		}
+	}
+	return note
+}
+
+// KDENotifier creates a Notifier using the legacy org.kde.Notifications service if it is on the bus,
+// falling back to org.freedesktop.Notifications otherwise, like New.
+// The methods and signals of both are the same, so the Notifier works the same for both.
+// opts are applied after the endpoint is chosen, so WithDestination and friends still take precedence.
+//
+// IsKDEExtension: true
+func KDENotifier(conn *dbus.Conn, opts ...option) (Notifier, error) {
+	present, err := hasBusName(conn, kdeDestination)
+	if err != nil {
+		return nil, err
+	}
+	if present {
+		opts = append([]option{
+			WithDestination(kdeDestination),
+			WithDBusInterface(kdeInterface),
+			WithDBusObjectPath(kdeObjectPath),
+		}, opts...)
+	}
+	return New(conn, opts...)
+}
+
+// hasBusName returns true if name is owned on the bus of conn.
+func hasBusName(conn *dbus.Conn, name string) (bool, error) {
+	names, err := listBusNames(conn)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
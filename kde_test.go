@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionTooltips(t *testing.T) {
+	hint := HintActionTooltip("open", "Open the file")
+	require.Equal(t, "x-kde-action-tooltip-open", hint.ID)
+	require.Equal(t, "Open the file", hint.Variant.Value())
+
+	n := Notification{
+		Summary: "multi",
+		Actions: []Action{{Key: "open", Label: "Open"}, {Key: "delete", Label: "Delete"}, {Key: "other", Label: "Other"}},
+	}
+	n.AddHint(HintActionTooltip("delete", "Already set"))
+
+	tooltips := map[string]string{"open": "Open the file", "delete": "Delete the file"}
+	withTooltips := addActionTooltips(n.Clone(), tooltips)
+	require.Equal(t, "Open the file", withTooltips.Hints["x-kde-action-tooltip-open"].Value())
+	require.Equal(t, "Already set", withTooltips.Hints["x-kde-action-tooltip-delete"].Value())
+	require.NotContains(t, withTooltips.Hints, "x-kde-action-tooltip-other")
+	require.Len(t, n.Hints, 1)
+}
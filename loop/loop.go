@@ -0,0 +1,74 @@
+// Package loop runs long lived goroutines, such as signal processing loops,
+// and stops them together with a clean up function.
+// It is the event loop lifecycle used by notify.Notifier.
+package loop
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a set of goroutines that are stopped together.
+// The zero value is not usable, use NewGroup. A Group is safe for concurrent use.
+type Group struct {
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+}
+
+// NewGroup creates an empty Group.
+func NewGroup() *Group {
+	return &Group{
+		done: make(chan struct{}),
+	}
+}
+
+// Start runs f in a new goroutine. done is closed as a signal for f to shut down.
+// g.Stop waits for f to finish before returning.
+func (g *Group) Start(f func(done <-chan struct{})) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		f(g.done)
+	}()
+}
+
+// Stop signals all goroutines started by g to shut down and waits for them to
+// finish. It then calls cleanup for further clean up. It is safe to be called
+// multiple times, cleanup is only called the first time, and its error is returned every time.
+func (g *Group) Stop(cleanup func() error) error {
+	return g.StopWithContext(context.Background(), cleanup)
+}
+
+// StopNoWait signals all goroutines started by g to shut down and calls cleanup right away,
+// without waiting for them to finish. It is meant for stopping g from one of its own goroutines,
+// where waiting would never end. Like Stop, cleanup is only called the first time.
+func (g *Group) StopNoWait(cleanup func() error) error {
+	g.closeOnce.Do(func() {
+		close(g.done)
+		g.err = cleanup()
+	})
+	return g.err
+}
+
+// StopWithContext is like Stop, but stops waiting for the goroutines when ctx is done.
+// cleanup is called in either case, and the error of ctx is returned if it was done first.
+func (g *Group) StopWithContext(ctx context.Context, cleanup func() error) error {
+	g.closeOnce.Do(func() {
+		close(g.done)
+		finished := make(chan struct{})
+		go func() {
+			g.wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+			g.err = cleanup()
+		case <-ctx.Done():
+			_ = cleanup()
+			g.err = ctx.Err()
+		}
+	})
+	return g.err
+}
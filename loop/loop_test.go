@@ -0,0 +1,72 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupStop(t *testing.T) {
+	g := NewGroup()
+	stopped := make(chan struct{})
+	g.Start(func(done <-chan struct{}) {
+		<-done
+		close(stopped)
+	})
+
+	cleanups := 0
+	errCleanup := errors.New("cleanup failed")
+	cleanup := func() error {
+		// the goroutines have finished before clean up
+		select {
+		case <-stopped:
+		default:
+			t.Error("cleanup called before goroutines finished")
+		}
+		cleanups++
+		return errCleanup
+	}
+	require.Equal(t, errCleanup, g.Stop(cleanup))
+	// only the first stop cleans up, later stops return the same error
+	require.Equal(t, errCleanup, g.Stop(cleanup))
+	require.Equal(t, 1, cleanups)
+}
+
+func TestGroupStopWithContext(t *testing.T) {
+	g := NewGroup()
+	release := make(chan struct{})
+	defer close(release)
+	g.Start(func(done <-chan struct{}) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cleanedUp := false
+	err := g.StopWithContext(ctx, func() error {
+		cleanedUp = true
+		return nil
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.True(t, cleanedUp)
+}
+
+func TestGroupStopNoWait(t *testing.T) {
+	g := NewGroup()
+	stopped := make(chan error, 1)
+	g.Start(func(done <-chan struct{}) {
+		// stopping from within the group must not wait for itself
+		stopped <- g.StopNoWait(func() error { return nil })
+		<-done
+	})
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StopNoWait did not return")
+	}
+	require.NoError(t, g.Stop(func() error { return errors.New("not called") }))
+}
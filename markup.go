@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// markupTagRegexp matches an opening, closing or self-closing markup tag.
+var markupTagRegexp = regexp.MustCompile(`</?([A-Za-z]+)(\s[^<>]*)?/?>`)
+
+// markupEntityRegexp matches an XML entity at the start of a string.
+var markupEntityRegexp = regexp.MustCompile(`^&(#[0-9]+|#x[0-9A-Fa-f]+|[A-Za-z]+);`)
+
+// allowedMarkupTags are the tags defined by the spec for servers supporting body-markup.
+// See: https://specifications.freedesktop.org/notification-spec/latest/ar01s04.html
+var allowedMarkupTags = map[string]bool{
+	"b":   true,
+	"i":   true,
+	"u":   true,
+	"a":   true,
+	"img": true,
+}
+
+// SanitizeBody prepares body for display by a notification server.
+//
+// With allowMarkup, tags defined by the spec (b, i, u, a and img) are kept, while other tags
+// and stray '<', '>' and '&' characters are escaped, so the server displays them as text.
+//
+// Without allowMarkup, for servers lacking CapabilityBodyMarkup, all tags are stripped
+// and entities are unescaped, leaving plain text.
+func SanitizeBody(body string, allowMarkup bool) string {
+	if !allowMarkup {
+		return html.UnescapeString(markupTagRegexp.ReplaceAllString(body, ""))
+	}
+
+	sb := &strings.Builder{}
+	last := 0
+	for _, m := range markupTagRegexp.FindAllStringSubmatchIndex(body, -1) {
+		escapeMarkupText(sb, body[last:m[0]])
+		tag := body[m[0]:m[1]]
+		if allowedMarkupTags[strings.ToLower(body[m[2]:m[3]])] {
+			sb.WriteString(tag)
+		} else {
+			escapeMarkupText(sb, tag)
+		}
+		last = m[1]
+	}
+	escapeMarkupText(sb, body[last:])
+	return sb.String()
+}
+
+// markupEscaper escapes the characters with a meaning in markup.
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// EscapeMarkup escapes '&', '<' and '>' in text, so servers supporting markup display it as is.
+// Use it for plain text that is made part of a markup body, e.g. with AppendBody.
+func EscapeMarkup(text string) string {
+	return markupEscaper.Replace(text)
+}
+
+// escapeMarkupText writes text to sb with '<' and '>' escaped, and '&' escaped unless it starts an entity.
+func escapeMarkupText(sb *strings.Builder, text string) {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '&':
+			if markupEntityRegexp.MatchString(text[i:]) {
+				sb.WriteByte('&')
+			} else {
+				sb.WriteString("&amp;")
+			}
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+}
+
+// WithAutoEscapeBody makes the Notifier strip markup from the body of notifications with SanitizeBody,
+// when the server does not report CapabilityBodyMarkup.
+// Capabilities are fetched on first send and cached.
+// A warning is logged the first time markup is stripped.
+func WithAutoEscapeBody() option {
+	return func(n *notifier) {
+		n.autoEscapeBody = true
+	}
+}
+
+// escapeBody strips markup from the body of note, if the server does not support it.
+func (n *notifier) escapeBody(note Notification) Notification {
+	caps, err := n.cachedCapabilities()
+	if err != nil {
+		n.log.Printf("error fetching capabilities, sending body as is: %v", err)
+		return note
+	}
+	if caps.Has(CapabilityBodyMarkup) {
+		return note
+	}
+	sanitized := SanitizeBody(note.Body, false)
+	if sanitized != note.Body {
+		n.escapeWarnOnce.Do(func() {
+			n.log.Printf("server does not support %v, stripping markup from notification bodies", CapabilityBodyMarkup)
+		})
+		note.Body = sanitized
+	}
+	return note
+}
@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	cases := []struct {
+		body, markup, plain string
+	}{
+		{"plain text", "plain text", "plain text"},
+		{"<b>bold</b> and <i>italic</i>", "<b>bold</b> and <i>italic</i>", "bold and italic"},
+		{`<a href="https://example.com">link</a>`, `<a href="https://example.com">link</a>`, "link"},
+		{"<script>x</script>", "&lt;script&gt;x&lt;/script&gt;", "x"},
+		{"1 < 2 > 0", "1 &lt; 2 &gt; 0", "1 < 2 > 0"},
+		{"fish &amp; chips & peas", "fish &amp; chips &amp; peas", "fish & chips & peas"},
+		{`<img src="a.png" alt="a"/>`, `<img src="a.png" alt="a"/>`, ""},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.markup, SanitizeBody(c.body, true), c.body)
+		require.Equal(t, c.plain, SanitizeBody(c.body, false), c.body)
+	}
+}
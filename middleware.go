@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+// NotifierMiddleware wraps a Notifier to change its behaviour, e.g. to alter notifications before they are sent.
+type NotifierMiddleware func(Notifier) Notifier
+
+// CapabilityFilterMiddleware strips the parts of notifications that a server with caps does not support,
+// using Notification.SanitizeForServer. The notifications passed in are not modified.
+func CapabilityFilterMiddleware(caps Capabilities) NotifierMiddleware {
+	return func(base Notifier) Notifier {
+		return &filteringNotifier{
+			Notifier: base,
+			filter: func(n Notification) Notification {
+				return n.SanitizeForServer(caps)
+			},
+		}
+	}
+}
+
+// AutoFilteringNotifier fetches the capabilities of the server once, and wraps base with CapabilityFilterMiddleware.
+// A server that does not implement GetCapabilities is assumed to support no optional capabilities.
+func AutoFilteringNotifier(base Notifier) (Notifier, error) {
+	caps, err := base.GetCapabilities()
+	if IsCapabilitiesUnsupported(err) {
+		caps, err = []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching capabilities: %w", err)
+	}
+	return CapabilityFilterMiddleware(caps)(base), nil
+}
+
+// imageHintIDs are the hints carrying an image, including the deprecated names of older spec versions.
+var imageHintIDs = []string{"image-data", "image-path", "image_data", "image_path", "icon_data"}
+
+// SanitizeForServer returns a copy of n without the parts that a server with caps does not support:
+//
+//   - the "sound-name" and "sound-file" hints, without CapabilitySound
+//   - the image hints, without CapabilityIconStatic or CapabilityIconMulti
+//   - Actions, without CapabilityActions
+//   - Body, without CapabilityBody
+//
+// n itself is not modified.
+func (n Notification) SanitizeForServer(caps Capabilities) Notification {
+	filtered := n.Clone()
+	if !caps.Has(CapabilitySound) {
+		delete(filtered.Hints, "sound-name")
+		delete(filtered.Hints, "sound-file")
+	}
+	if !caps.Has(CapabilityIconStatic) && !caps.Has(CapabilityIconMulti) {
+		for _, id := range imageHintIDs {
+			delete(filtered.Hints, id)
+		}
+	}
+	if !caps.Has(CapabilityActions) {
+		filtered.Actions = nil
+	}
+	if !caps.Has(CapabilityBody) {
+		filtered.Body = ""
+	}
+	return filtered
+}
+
+// filteringNotifier applies filter to all notifications before they are sent by the embedded Notifier.
+type filteringNotifier struct {
+	Notifier
+	filter func(Notification) Notification
+}
+
+func (f *filteringNotifier) SendNotification(n Notification) (uint32, error) {
+	return f.Notifier.SendNotification(f.filter(n))
+}
+
+func (f *filteringNotifier) SendNotificationSplit(n Notification) ([]uint32, error) {
+	return f.Notifier.SendNotificationSplit(f.filter(n))
+}
+
+// Unwrap returns the Notifier f sends through.
+func (f *filteringNotifier) Unwrap() Notifier {
+	return f.Notifier
+}
+
+func (f *filteringNotifier) Clone(opts ...option) (Notifier, error) {
+	clone, err := f.Notifier.Clone(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &filteringNotifier{Notifier: clone, filter: f.filter}, nil
+}
+
+func (f *filteringNotifier) SendAndWaitForClose(ctx context.Context, n Notification) (NotificationClosedSignal, error) {
+	return f.Notifier.SendAndWaitForClose(ctx, f.filter(n))
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeForServer(t *testing.T) {
+	n := Notification{
+		Summary: "summary",
+		Body:    "body",
+		Actions: []Action{{Key: "open", Label: "Open"}},
+	}
+	n.AddHint(HintSoundWithName("bell"))
+	n.AddHint(HintImageFilePath("/tmp/image.png"))
+	n.SetUrgency(UrgencyCritical)
+
+	filtered := n.SanitizeForServer(Capabilities{})
+	require.Empty(t, filtered.Body)
+	require.Nil(t, filtered.Actions)
+	require.NotContains(t, filtered.Hints, "sound-name")
+	require.NotContains(t, filtered.Hints, "image-path")
+	require.Contains(t, filtered.Hints, "urgency")
+	// the original is not modified
+	require.Equal(t, "body", n.Body)
+	require.Len(t, n.Actions, 1)
+	require.Contains(t, n.Hints, "sound-name")
+
+	all := Capabilities{CapabilitySound, CapabilityActions, CapabilityBody, CapabilityIconStatic}
+	require.Equal(t, n, n.SanitizeForServer(all))
+	require.Contains(t, n.SanitizeForServer(Capabilities{CapabilityIconMulti}).Hints, "image-path")
+}
@@ -1,14 +1,26 @@
 package notify
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/godbus/dbus/v5"
+
+	"github.com/esiqveland/notify/loop"
 )
 
 const (
@@ -16,16 +28,17 @@ const (
 	dbusAddMatch               = "org.freedesktop.DBus.AddMatch"
 	dbusObjectPath             = "/org/freedesktop/Notifications" // the DBUS object path
 	dbusNotificationsInterface = "org.freedesktop.Notifications"  // DBUS Interface
-	signalNotificationClosed   = "org.freedesktop.Notifications.NotificationClosed"
-	signalActionInvoked        = "org.freedesktop.Notifications.ActionInvoked"
-	callGetCapabilities        = "org.freedesktop.Notifications.GetCapabilities"
-	callCloseNotification      = "org.freedesktop.Notifications.CloseNotification"
-	callNotify                 = "org.freedesktop.Notifications.Notify"
-	callGetServerInformation   = "org.freedesktop.Notifications.GetServerInformation"
-
-	channelBufferSize = 10
+	signalNotificationClosed   = "NotificationClosed"
+	signalActionInvoked        = "ActionInvoked"
+	methodGetCapabilities      = "GetCapabilities"
+	methodCloseNotification    = "CloseNotification"
+	methodNotify               = "Notify"
+	methodGetServerInformation = "GetServerInformation"
 )
 
+// channelBufferSize is the default number of signals buffered while signal handlers are running.
+var channelBufferSize = 10
+
 // Deprecated: use Hint
 type Variant = Hint
 
@@ -35,10 +48,21 @@ type Hint struct {
 	Variant dbus.Variant
 }
 
-func HintSoundWithName(soundName string) Hint {
+// ToHint returns h itself, so Hint implements HintProvider.
+func (h Hint) ToHint() Hint {
+	return h
+}
+
+// HintProvider is implemented by all types that can be added to a Notification with AddHint.
+type HintProvider interface {
+	ToHint() Hint
+}
+
+// HintActionIcons makes the server interpret action keys as icon names, see Action.Icon.
+func HintActionIcons(enabled bool) Hint {
 	return Hint{
-		ID:      "sound-name",
-		Variant: dbus.MakeVariant(soundName),
+		ID:      "action-icons",
+		Variant: dbus.MakeVariant(enabled),
 	}
 }
 
@@ -52,11 +76,41 @@ func HintUrgency(urgency Urgency) Hint {
 type Urgency byte
 
 const (
-	UrgencyLow      Urgency = 0
-	UrgencyNormal   Urgency = 1
-	UrgencyCritical Urgency = 2
+	UrgencyLow Urgency = iota
+	UrgencyNormal
+	UrgencyCritical
+
+	// urgencyCount is the number of Urgency values above. New values must be added before it.
+	urgencyCount
 )
 
+// knownUrgencies lists all Urgency values defined by the spec.
+var knownUrgencies = [...]Urgency{UrgencyLow, UrgencyNormal, UrgencyCritical}
+
+// Compile-time check that knownUrgencies lists every Urgency constant:
+// adding a constant to the block above without adding it here fails to compile.
+var _ = [1]struct{}{}[len(knownUrgencies)-int(urgencyCount)]
+
+// Ptr returns a pointer to a copy of u, for setting Notification.Urgency.
+func (u Urgency) Ptr() *Urgency {
+	return &u
+}
+
+// IsKnown returns true if u is one of UrgencyLow, UrgencyNormal or UrgencyCritical.
+func (u Urgency) IsKnown() bool {
+	for _, known := range knownUrgencies {
+		if u == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AllUrgencies returns all known Urgency values, in increasing order.
+func AllUrgencies() []Urgency {
+	return append([]Urgency(nil), knownUrgencies[:]...)
+}
+
 // HintImageFilePath sends a filepath to the notification server as the file of the icon.
 // See also: https://specifications.freedesktop.org/notification-spec/latest/ar01s05.html
 func HintImageFilePath(imageAbsolutePath string) Hint {
@@ -67,34 +121,106 @@ func HintImageFilePath(imageAbsolutePath string) Hint {
 	}
 }
 
-// dbusImageData encodes Hint for "image-data" iiibiiay
+// ImageData encodes Hint for "image-data" iiibiiay
 // Data format: https://specifications.freedesktop.org/notification-spec/latest/ar01s05.html
-type dbusImageData struct {
-	Width         int32  // i
-	Height        int32  // i
+type ImageData struct {
+	Width  int32 // i
+	Height int32 // i
+	// RowStride is the distance in bytes between the start of two rows in Data.
+	// The spec requires it to be at least Width * Channels * BitsPerSample / 8,
+	// any bytes beyond that in a row are padding.
 	RowStride     int32  // i
 	HasAlpha      bool   // b
 	BitsPerSample int32  // i
-	Samples       int32  // i
-	Image         []byte // ay
+	Channels      int32  // i
+	Data          []byte // ay
 }
 
-func HintImageDataRGBA(img *image.RGBA) Hint {
-	imageData := dbusImageData{
-		Width:         int32(img.Rect.Max.X),
-		Height:        int32(img.Rect.Max.Y),
-		RowStride:     int32(img.Stride),
+// FromRGBA creates ImageData holding the pixels of img.
+// img may be a sub-image, in which case only the pixels within img.Rect are included.
+func FromRGBA(img *image.RGBA) ImageData {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	return ImageData{
+		Width:         int32(width),
+		Height:        int32(height),
+		RowStride:     computeRowStride(img),
 		HasAlpha:      true,
 		BitsPerSample: 8,
-		Samples:       4,
-		Image:         img.Pix,
+		Channels:      4,
+		Data:          rgbaPixels(img),
 	}
+}
+
+// ToVariant wraps the image data in a dbus.Variant with signature (iiibiiay).
+func (d ImageData) ToVariant() dbus.Variant {
+	return dbus.MakeVariant(d)
+}
+
+// Valid checks that the dimensions and row stride of the image add up with the length of Data,
+// and that Channels is 3, or 4 with HasAlpha.
+func (d ImageData) Valid() error {
+	if d.Width <= 0 || d.Height <= 0 {
+		return fmt.Errorf("invalid image dimensions: %dx%d", d.Width, d.Height)
+	}
+	if d.BitsPerSample != 8 {
+		return fmt.Errorf("invalid bits per sample: %d, only 8 is supported", d.BitsPerSample)
+	}
+	if wantChannels := imageChannels(d.HasAlpha); d.Channels != wantChannels {
+		return fmt.Errorf("invalid channels: %d, must be %d with alpha %v", d.Channels, wantChannels, d.HasAlpha)
+	}
+	// computed in int, so large widths do not overflow
+	rowLen := int(d.Width) * int(d.Channels) * int(d.BitsPerSample) / 8
+	if int(d.RowStride) < rowLen {
+		return fmt.Errorf("row stride %d is less than width*channels*bytes per sample: %d", d.RowStride, rowLen)
+	}
+	// the last row needs no padding
+	if size := int(d.RowStride)*(int(d.Height)-1) + rowLen; len(d.Data) < size {
+		return fmt.Errorf("image data length %d is less than row stride*(height-1) + row length: %d", len(d.Data), size)
+	}
+	return nil
+}
+
+// imageChannels returns the number of channels of an RGB image, with or without alpha.
+func imageChannels(hasAlpha bool) int32 {
+	if hasAlpha {
+		return 4
+	}
+	return 3
+}
+
+// HintImageDataRGBA sends the pixels of img inline in the "image-data" hint.
+// img may be a sub-image, in which case only the pixels within img.Rect are sent.
+func HintImageDataRGBA(img *image.RGBA) Hint {
 	return Hint{
 		ID:      "image-data",
-		Variant: dbus.MakeVariant(imageData),
+		Variant: FromRGBA(img).ToVariant(),
 	}
 }
 
+// computeRowStride returns the row stride of the pixels of img as sent by FromRGBA.
+// Rows are packed by rgbaPixels, so this is not img.Stride, which for a sub-image
+// is the stride of its parent image.
+func computeRowStride(img *image.RGBA) int32 {
+	return int32(img.Rect.Dx() * 4)
+}
+
+// rgbaPixels returns the pixels within img.Rect, packed row by row without padding.
+// For a sub-image, img.Stride is the stride of the parent image, so every row
+// must be copied out separately to not include pixels outside of img.Rect.
+func rgbaPixels(img *image.RGBA) []byte {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	rowLen := width * 4
+	if img.Stride == rowLen {
+		return img.Pix[:rowLen*height]
+	}
+	data := make([]byte, 0, rowLen*height)
+	for y := 0; y < height; y++ {
+		rowStart := y * img.Stride
+		data = append(data, img.Pix[rowStart:rowStart+rowLen]...)
+	}
+	return data
+}
+
 // Notification holds all information needed for creating a notification
 type Notification struct {
 	AppName string
@@ -109,56 +235,364 @@ type Notification struct {
 	// Actions are tuples of (action_key, label), e.g.: []Action{"cancel", "Cancel", "open", "Open"}
 	Actions []Action
 	Hints   map[string]dbus.Variant
+	// Urgency is sent as the urgency hint, overriding any urgency set in Hints.
+	// nil leaves the urgency unset, and up to the server. Optional:
+	//
+	//	n.Urgency = notify.UrgencyCritical.Ptr()
+	Urgency *Urgency
 	// ExpireTimeout: duration to show notification. See also ExpireTimeoutSetByNotificationServer and ExpireTimeoutNever.
 	ExpireTimeout time.Duration
 }
 
+// SetUrgency sets the urgency hint of n.
+//
+// Deprecated: set the Urgency field instead. Replace
+//
+//	n.SetUrgency(notify.UrgencyCritical)
+//
+// with
+//
+//	n.Urgency = notify.UrgencyCritical.Ptr()
+//
+// Both send the same hint. When both are set, the Urgency field takes precedence.
 func (n *Notification) SetUrgency(urgency Urgency) {
 	n.AddHint(HintUrgency(urgency))
 }
 
-func (n *Notification) AddHint(hint Hint) {
+// GetUrgency reads the urgency of n, from the Urgency field if set, otherwise from the urgency hint.
+// Returns UrgencyNormal and false if neither is set, or the hint is not a byte.
+func GetUrgency(n Notification) (Urgency, bool) {
+	if n.Urgency != nil {
+		return *n.Urgency, true
+	}
+	variant, ok := n.Hints["urgency"]
+	if !ok {
+		return UrgencyNormal, false
+	}
+	urgency, ok := variant.Value().(byte)
+	if !ok {
+		return UrgencyNormal, false
+	}
+	return Urgency(urgency), true
+}
+
+// AddHint adds a Hint, or any other HintProvider such as SoundVariant, to the notification.
+func (n *Notification) AddHint(provider HintProvider) {
+	hint := provider.ToHint()
 	if n.Hints == nil {
 		n.Hints = map[string]dbus.Variant{}
 	}
 	n.Hints[hint.ID] = hint.Variant
 }
 
+// TruncateBody trims Body to at most maxBytes bytes of UTF-8, including ellipsis, which is appended if
+// the body was truncated. Multi-byte runes are never cut in half.
+// Notification servers often have undocumented limits on body length, typically somewhere between 1 and 4 KB.
+func (n *Notification) TruncateBody(maxBytes int, ellipsis string) *Notification {
+	n.Body = truncateString(n.Body, maxBytes, ellipsis)
+	return n
+}
+
+// TruncateSummary trims Summary to at most maxBytes bytes of UTF-8, including ellipsis, which is appended if
+// the summary was truncated. Multi-byte runes are never cut in half.
+func (n *Notification) TruncateSummary(maxBytes int, ellipsis string) *Notification {
+	n.Summary = truncateString(n.Summary, maxBytes, ellipsis)
+	return n
+}
+
+// TruncateBodyDefault truncates the body of n to 1024 bytes using "…" as ellipsis.
+func TruncateBodyDefault(n *Notification) *Notification {
+	return n.TruncateBody(1024, "…")
+}
+
+// ErrBodyTooLong is returned by AppendBody and AppendBodyLine when the body would exceed MaxBodyBytes.
+var ErrBodyTooLong = errors.New("notify: body too long")
+
+// AppendBody appends text to Body on a line of its own, separated by a newline unless Body is empty.
+// Returns ErrBodyTooLong, leaving Body unchanged, if the body would exceed MaxBodyBytes.
+//
+// text is appended as is. If the body contains markup, escape plain text with EscapeMarkup first.
+func (n *Notification) AppendBody(text string) error {
+	if n.Body == "" {
+		return n.appendBody(text)
+	}
+	return n.appendBody("\n" + text)
+}
+
+// AppendBodyLine appends text followed by a newline to Body, like AppendBody.
+func (n *Notification) AppendBodyLine(text string) error {
+	return n.appendBody(text + "\n")
+}
+
+func (n *Notification) appendBody(s string) error {
+	if len(n.Body)+len(s) > maxBodyBytes {
+		return ErrBodyTooLong
+	}
+	n.Body += s
+	return nil
+}
+
+// truncateString cuts s to at most maxBytes bytes, ending with ellipsis if s was cut.
+// If ellipsis itself does not fit within maxBytes, it is left out.
+func truncateString(s string, maxBytes int, ellipsis string) string {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	if len(s) <= maxBytes {
+		return s
+	}
+	if len(ellipsis) > maxBytes {
+		ellipsis = ""
+	}
+	cut := maxBytes - len(ellipsis)
+	// back off to the start of a rune, so we never split a multi-byte sequence
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
+
+// MergeFrom returns a new Notification based on n, where every non-zero field of other overrides the one in n.
+// This is useful for a "template + override" pattern, where n holds common fields like AppName and AppIcon.
+//
+// Hints are merged, with the hints of other taking precedence.
+// Actions of other replace those of n entirely if non-nil.
+// An ExpireTimeout of 0 in other keeps the ExpireTimeout of n, and is not interpreted as ExpireTimeoutNever.
+func (n Notification) MergeFrom(other Notification) Notification {
+	merged := n
+	if other.AppName != "" {
+		merged.AppName = other.AppName
+	}
+	if other.ReplacesID != 0 {
+		merged.ReplacesID = other.ReplacesID
+	}
+	if other.AppIcon != "" {
+		merged.AppIcon = other.AppIcon
+	}
+	if other.Summary != "" {
+		merged.Summary = other.Summary
+	}
+	if other.Body != "" {
+		merged.Body = other.Body
+	}
+	if other.Actions != nil {
+		merged.Actions = other.Actions
+	}
+	if other.Urgency != nil {
+		merged.Urgency = other.Urgency.Ptr()
+	}
+	if other.ExpireTimeout != 0 {
+		merged.ExpireTimeout = other.ExpireTimeout
+	}
+	if n.Hints != nil || other.Hints != nil {
+		merged.Hints = make(map[string]dbus.Variant, len(n.Hints)+len(other.Hints))
+		for k, v := range n.Hints {
+			merged.Hints[k] = v
+		}
+		for k, v := range other.Hints {
+			merged.Hints[k] = v
+		}
+	}
+	return merged
+}
+
+// Clone returns a copy of n that does not share the Actions slice or Hints map with n.
+func (n Notification) Clone() Notification {
+	clone := n
+	if n.Actions != nil {
+		clone.Actions = append([]Action{}, n.Actions...)
+	}
+	if n.Hints != nil {
+		clone.Hints = make(map[string]dbus.Variant, len(n.Hints))
+		for k, v := range n.Hints {
+			clone.Hints[k] = v
+		}
+	}
+	if n.Urgency != nil {
+		clone.Urgency = n.Urgency.Ptr()
+	}
+	return clone
+}
+
+// WithReplace returns a clone of n that replaces the notification with existingID when sent:
+//
+//	updated := original.WithReplace(sentID)
+//
+// n itself is not modified. Change Summary, Body etc. on the returned clone to update the notification.
+func (n Notification) WithReplace(existingID uint32) Notification {
+	clone := n.Clone()
+	clone.ReplacesID = existingID
+	return clone
+}
+
 // ExpireTimeoutSetByNotificationServer used as ExpireTimeout to leave expiration up to the notification server.
 // Expiration is sent as number of millis.
 // When -1, the notification's expiration time is dependent on the notification server's settings, and may vary for the type of notification. If 0, never expire.
 const ExpireTimeoutSetByNotificationServer = time.Millisecond * -1
 const ExpireTimeoutNever time.Duration = 0
 
+// IsExpireNever returns true if d is ExpireTimeoutNever.
+// Note that this is also the zero value, so an ExpireTimeout that was never set reports true as well.
+func IsExpireNever(d time.Duration) bool {
+	return d == ExpireTimeoutNever
+}
+
+// IsExpireServerDefault returns true if d is ExpireTimeoutSetByNotificationServer.
+func IsExpireServerDefault(d time.Duration) bool {
+	return d == ExpireTimeoutSetByNotificationServer
+}
+
 // Action holds key and label for user action buttons.
 type Action struct {
 	// Key is the identifier for the action, used for signaling back which action was selected
 	Key string
 	// Label is the localized string that will be displayed to the user
 	Label string
+	// Icon is an icon name shown instead of Label, which is then used as the accessibility label.
+	//
+	// The spec only supports icons for all actions of a notification, with the action-icons hint.
+	// When any action has an Icon, the hint is added, and the icon name is sent in place of Key, as the spec
+	// requires. Servers then report Icon rather than Key in the ActionInvoked signal, which MatchesAction accounts for.
+	// Actions without an Icon are sent unchanged, and are shown as text by servers falling back to Label
+	// when no icon matches the key. Mixing icon and text actions is an extension of the spec,
+	// and servers vary in how they show it; check for CapabilityActionIcons.
+	Icon string
 }
 
+// ActionKeyDefault is the key of the default action, see NewDefaultAction.
+const ActionKeyDefault = "default"
+
 // NewDefaultAction creates a new default action.
 // The default action is usually invoked by clicking on the notification.
 // The label can be anything, but implementations are free whether to display it.
 func NewDefaultAction(label string) Action {
-	return Action{Key: "default", Label: label}
+	return Action{Key: ActionKeyDefault, Label: label}
+}
+
+// SetDefaultAction sets the default action of n with label, replacing an existing default action.
+// The default action is usually invoked by clicking on the body of the notification, and may not be
+// shown as a button. Server support varies, check for CapabilityActions.
+func (n *Notification) SetDefaultAction(label string) *Notification {
+	for i, a := range n.Actions {
+		if a.Key == ActionKeyDefault {
+			n.Actions[i].Label = label
+			return n
+		}
+	}
+	n.Actions = append(n.Actions, NewDefaultAction(label))
+	return n
+}
+
+// ActionsMap returns the actions of n keyed by Action.Key.
+// If several actions share the same key, the last one wins.
+func (n Notification) ActionsMap() map[string]Action {
+	m := make(map[string]Action, len(n.Actions))
+	for _, a := range n.Actions {
+		m[a.Key] = a
+	}
+	return m
+}
+
+// FindAction returns the first action of n with key.
+func (n Notification) FindAction(key string) (Action, bool) {
+	for _, a := range n.Actions {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
+// ActionKeys returns the keys of all actions of n, in order.
+func (n Notification) ActionKeys() []string {
+	keys := make([]string, 0, len(n.Actions))
+	for _, a := range n.Actions {
+		keys = append(keys, a.Key)
+	}
+	return keys
+}
+
+// ActionLabels returns the labels of all actions of n, in order.
+func (n Notification) ActionLabels() []string {
+	labels := make([]string, 0, len(n.Actions))
+	for _, a := range n.Actions {
+		labels = append(labels, a.Label)
+	}
+	return labels
+}
+
+// ActionsFromOrderedMap builds actions in the order of keys, with labels looked up in labels.
+// Servers may render actions in the order they are sent, which a map alone can not preserve.
+// Returns an error if a key has no label.
+func ActionsFromOrderedMap(keys []string, labels map[string]string) ([]Action, error) {
+	actions := make([]Action, 0, len(keys))
+	for _, key := range keys {
+		label, ok := labels[key]
+		if !ok {
+			return nil, fmt.Errorf("missing label for action key %q", key)
+		}
+		actions = append(actions, Action{Key: key, Label: label})
+	}
+	return actions, nil
 }
 
 // SendNotification is provided for convenience.
 // Use if you only want to deliver a notification and do not care about actions or events.
 func SendNotification(conn *dbus.Conn, note Notification) (uint32, error) {
+	return sendNotification(context.Background(), conn, defaultEndpoint, note)
+}
+
+// SendNotificationWithHints sends note with hints added, like SendNotification.
+// hints overwrite hints of note with the same ID. note itself is not modified.
+func SendNotificationWithHints(conn *dbus.Conn, note Notification, hints ...HintProvider) (uint32, error) {
+	withHints := note.Clone()
+	for _, h := range hints {
+		withHints.AddHint(h)
+	}
+	return sendNotification(context.Background(), conn, defaultEndpoint, withHints)
+}
+
+func sendNotification(ctx context.Context, conn *dbus.Conn, e endpoint, note Notification) (uint32, error) {
 	actions := []string{}
+	actionIcons := false
+
+	for _, a := range note.Actions {
+		key := a.Key
+		if a.Icon != "" {
+			// with action-icons, the server takes the key as icon name
+			key, actionIcons = a.Icon, true
+		}
+		actions = append(actions, key, a.Label)
+	}
 
-	for i := range note.Actions {
-		actions = append(actions, note.Actions[i].Key, note.Actions[i].Label)
+	// some servers do not accept a missing hints dict
+	hints := note.Hints
+	if hints == nil {
+		hints = map[string]dbus.Variant{}
+	}
+	if note.Urgency != nil || actionIcons {
+		// copy, so the hints of the caller are left untouched
+		withExtra := make(map[string]dbus.Variant, len(hints)+2)
+		for k, v := range hints {
+			withExtra[k] = v
+		}
+		if note.Urgency != nil {
+			urgency := HintUrgency(*note.Urgency)
+			withExtra[urgency.ID] = urgency.Variant
+		}
+		if actionIcons {
+			icons := HintActionIcons(true)
+			withExtra[icons.ID] = icons.Variant
+		}
+		hints = withExtra
 	}
 
 	durationMs := int32(note.ExpireTimeout.Milliseconds())
 
-	obj := conn.Object(dbusNotificationsInterface, dbusObjectPath)
-	call := obj.Call(
-		callNotify,
+	obj := e.object(conn)
+	call := obj.CallWithContext(
+		ctx,
+		e.member(methodNotify),
 		0,
 		note.AppName,
 		note.ReplacesID,
@@ -166,11 +600,11 @@ func SendNotification(conn *dbus.Conn, note Notification) (uint32, error) {
 		note.Summary,
 		note.Body,
 		actions,
-		note.Hints,
+		hints,
 		durationMs,
 	)
 	if call.Err != nil {
-		return 0, fmt.Errorf("error sending notification: %w", call.Err)
+		return 0, fmt.Errorf("error sending notification: %w", callError(call.Err))
 	}
 	var ret uint32
 	err := call.Store(&ret)
@@ -201,19 +635,29 @@ type ServerInformation struct {
 //			version		 STRING	  The server's version number.
 //			spec_version STRING	  The specification version the server is compliant with.
 func GetServerInformation(conn *dbus.Conn) (ServerInformation, error) {
-	obj := conn.Object(dbusNotificationsInterface, dbusObjectPath)
+	return GetServerInformationContext(context.Background(), conn)
+}
+
+// GetServerInformationContext is like GetServerInformation, with the dbus call bound to ctx.
+func GetServerInformationContext(ctx context.Context, conn *dbus.Conn) (ServerInformation, error) {
+	return getServerInformation(ctx, conn, defaultEndpoint)
+}
+
+func getServerInformation(ctx context.Context, conn *dbus.Conn, e endpoint) (ServerInformation, error) {
+	obj := e.object(conn)
 	if obj == nil {
 		return ServerInformation{}, errors.New("error creating dbus call object")
 	}
-	call := obj.Call(callGetServerInformation, 0)
+	method := e.member(methodGetServerInformation)
+	call := obj.CallWithContext(ctx, method, 0)
 	if call.Err != nil {
-		return ServerInformation{}, fmt.Errorf("error calling %v: %v", callGetServerInformation, call.Err)
+		return ServerInformation{}, fmt.Errorf("error calling %v: %w", method, callError(call.Err))
 	}
 
 	ret := ServerInformation{}
 	err := call.Store(&ret.Name, &ret.Vendor, &ret.Version, &ret.SpecVersion)
 	if err != nil {
-		return ret, fmt.Errorf("error reading %v return values: %v", callGetServerInformation, err)
+		return ret, fmt.Errorf("error reading %v return values: %v", method, err)
 	}
 	return ret, nil
 }
@@ -225,10 +669,22 @@ func GetServerInformation(conn *dbus.Conn) (ServerInformation, error) {
 // See also: https://developer.gnome.org/notification-spec/
 // GetCapabilities provide an exported method for this operation
 func GetCapabilities(conn *dbus.Conn) ([]string, error) {
-	obj := conn.Object(dbusNotificationsInterface, dbusObjectPath)
-	call := obj.Call(callGetCapabilities, 0)
+	return GetCapabilitiesContext(context.Background(), conn)
+}
+
+// GetCapabilitiesContext is like GetCapabilities, with the dbus call bound to ctx.
+func GetCapabilitiesContext(ctx context.Context, conn *dbus.Conn) ([]string, error) {
+	return getCapabilities(ctx, conn, defaultEndpoint)
+}
+
+func getCapabilities(ctx context.Context, conn *dbus.Conn, e endpoint) ([]string, error) {
+	obj := e.object(conn)
+	call := obj.CallWithContext(ctx, e.member(methodGetCapabilities), 0)
+	if dbusErrorName(call.Err) == dbusErrorUnknownMethod {
+		return []string{}, fmt.Errorf("%w: %v", ErrGetCapabilitiesNotSupported, call.Err)
+	}
 	if call.Err != nil {
-		return []string{}, call.Err
+		return []string{}, callError(call.Err)
 	}
 	var ret []string
 	err := call.Store(&ret)
@@ -238,6 +694,33 @@ func GetCapabilities(conn *dbus.Conn) ([]string, error) {
 	return ret, nil
 }
 
+// CloseNotification is provided for convenience, to close a notification sent with SendNotification.
+// When the server reports the ID as invalid, a *NotificationNotFoundError is returned, see IsNotFound.
+// See also: Notifier.CloseNotification
+func CloseNotification(conn *dbus.Conn, id uint32) error {
+	return CloseNotificationContext(context.Background(), conn, id)
+}
+
+// CloseNotificationContext is like CloseNotification, with the dbus call bound to ctx.
+func CloseNotificationContext(ctx context.Context, conn *dbus.Conn, id uint32) error {
+	if id == 0 {
+		return ErrInvalidNotificationID
+	}
+	return closeNotification(ctx, conn, defaultEndpoint, id)
+}
+
+func closeNotification(ctx context.Context, conn *dbus.Conn, e endpoint, id uint32) error {
+	obj := e.object(conn)
+	call := obj.CallWithContext(ctx, e.member(methodCloseNotification), 0, id)
+	if call.Err != nil {
+		if dbusErrorsInvalidID[dbusErrorName(call.Err)] {
+			return &NotificationNotFoundError{ID: id}
+		}
+		return callError(call.Err)
+	}
+	return nil
+}
+
 // Notifier is an interface implementing the operations supported by the
 // Freedesktop DBus Notifications object.
 //
@@ -255,10 +738,23 @@ func GetCapabilities(conn *dbus.Conn) ([]string, error) {
 // to shut down event loop and cleanup dbus registration.
 type Notifier interface {
 	SendNotification(n Notification) (uint32, error)
+	SendNotificationSplit(n Notification) ([]uint32, error)
 	GetCapabilities() ([]string, error)
 	GetServerInformation() (ServerInformation, error)
-	CloseNotification(id uint32) (bool, error)
+	ServerCapabilities() (ServerCapabilities, error)
+	Stats() NotifierStats
+	CloseNotification(id uint32) error
+	CloseNotificationSync(ctx context.Context, id uint32) (NotificationClosedSignal, error)
+	SendAndWaitForClose(ctx context.Context, n Notification) (NotificationClosedSignal, error)
+	WaitForSignal(ctx context.Context, id uint32) (NotificationEvent, error)
+	History() []HistoryEntry
+	FlushCache()
+	EventWriter(w io.Writer) func()
+	Shutdown(ctx context.Context) error
 	Close() error
+	IsOpen() bool
+	Clone(opts ...option) (Notifier, error)
+	Conn() *dbus.Conn
 }
 
 // NotificationClosedHandler is called when we receive a NotificationClosed signal
@@ -273,6 +769,12 @@ type NotificationClosedHandler func(*NotificationClosedSignal)
 // and does at least happen on XFCE4.
 type ActionInvokedHandler func(*ActionInvokedSignal)
 
+// NotificationClosedHandlerCtx is a NotificationClosedHandler receiving the context of WithSignalContext.
+type NotificationClosedHandlerCtx func(context.Context, *NotificationClosedSignal)
+
+// ActionInvokedHandlerCtx is an ActionInvokedHandler receiving the context of WithSignalContext.
+type ActionInvokedHandlerCtx func(context.Context, *ActionInvokedSignal)
+
 // ActionInvokedSignal holds data from any signal received regarding Actions invoked
 type ActionInvokedSignal struct {
 	// ID of the Notification the action was invoked for
@@ -281,14 +783,143 @@ type ActionInvokedSignal struct {
 	ActionKey string
 }
 
+// IsDefault returns true if the signal was invoked for the default action,
+// usually by clicking on the body of the notification.
+func (s *ActionInvokedSignal) IsDefault() bool {
+	return s.ActionKey == ActionKeyDefault
+}
+
+// MatchesAction returns true if the signal was invoked for action a.
+// For an action with an Icon, the signal carries the icon name, see Action.Icon.
+func (s *ActionInvokedSignal) MatchesAction(a Action) bool {
+	if a.Icon != "" {
+		return s.ActionKey == a.Icon
+	}
+	return s.ActionKey == a.Key
+}
+
+// MatchesAnyAction returns true if the signal was invoked for any of actions.
+func (s *ActionInvokedSignal) MatchesAnyAction(actions []Action) bool {
+	_, ok := s.ActionLabel(actions)
+	return ok
+}
+
+// ActionLabel looks up the label of the invoked action in actions.
+func (s *ActionInvokedSignal) ActionLabel(actions []Action) (string, bool) {
+	a, ok := s.FindAction(actions)
+	return a.Label, ok
+}
+
+// FindAction looks up the invoked action in actions, see MatchesAction.
+func (s *ActionInvokedSignal) FindAction(actions []Action) (Action, bool) {
+	for _, a := range actions {
+		if s.MatchesAction(a) {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
 // notifier implements Notifier interface
 type notifier struct {
-	conn     *dbus.Conn
+	conn *dbus.Conn
+	// closed is set to 1 by Shutdown, accessed atomically
+	closed int32
+	// intake receives signals from dbus. It is signal itself, unless dropSignals is set,
+	// in which case signals are relayed from intake to signal.
+	intake   chan *dbus.Signal
 	signal   chan *dbus.Signal
 	onClosed NotificationClosedHandler
 	onAction ActionInvokedHandler
 	log      logger
-	group    *group
+	group    *loop.Group
+	endpoint endpoint
+	// portal is set by PortalNotifier, to use the XDG desktop portal API at endpoint
+	portal bool
+	stats  *notifierStats
+	// ctx bounds dbus calls without a context of their own
+	ctx context.Context
+	// callTimeout bounds dbus calls without a context of their own, unless 0
+	callTimeout time.Duration
+	// opts the notifier was created with, reused by Clone
+	opts []option
+	// history is nil unless WithHistory is used
+	history *history
+	// historyTTL evicts history entries older than it, unless 0
+	historyTTL time.Duration
+	// historyMaxMemory limits the estimated memory use of history, unless 0
+	historyMaxMemory int
+	// onEvict is called with entries evicted from history, unless nil
+	onEvict func(HistoryEntry)
+	events  eventWriters
+
+	capsCache *ttlCache
+	// infoCache is nil unless WithCacheServerInformation is used
+	infoCache      *ttlCache
+	autoEscapeBody bool
+	escapeWarnOnce sync.Once
+	// appNameDefault is used for notifications without an AppName
+	appNameDefault string
+	// appIconDefault is used for notifications without an AppIcon
+	appIconDefault string
+	// iconCache is nil unless WithIconAutoResolve is used
+	iconCache *appIconCache
+	// maxNotificationSize refuses notifications estimated larger than it, unless 0
+	maxNotificationSize int
+	// bodySplitMax splits bodies longer than it into several notifications, unless 0
+	bodySplitMax int
+	// actionTooltips are added to actions of sent notifications, keyed by action key
+	actionTooltips map[string]string
+
+	signalBufferSize int
+	// onDaemonRestart is nil unless WithOnDaemonRestart is used
+	onDaemonRestart func()
+	// signalCtx is passed to the handlers of WithOnActionCtx and WithOnClosedCtx
+	signalCtx context.Context
+	// actionCh is nil unless WithActionChannel is used
+	actionCh chan<- *ActionInvokedSignal
+	// closedCh is nil unless WithClosedChannel is used
+	closedCh chan<- *NotificationClosedSignal
+	// onPanic is nil unless WithOnPanic is used
+	onPanic func(recovered interface{})
+	// handlerTimeout stops waiting for signal handlers after it, unless 0
+	handlerTimeout time.Duration
+	// loopGoroutine holds the goroutineID of the event loop, which runs the signal handlers, see Shutdown
+	loopGoroutine atomic.Value
+	// listenerTTL evicts listeners waiting longer than it, unless 0
+	listenerTTL time.Duration
+	// dropSignals drops signals when the signal buffer is full
+	dropSignals bool
+
+	// sessionBusPrivate makes NewSessionBusNotifier open a private connection
+	sessionBusPrivate bool
+	// dialTimeout bounds connecting in NewSessionBusNotifier, unless 0
+	dialTimeout time.Duration
+	// ownsConn is true when conn was opened by the notifier, and must be closed by it
+	ownsConn bool
+
+	// waitersMu guards closeWaiters, eventWaiters, waiterSince, recentClosed and recentClosedOrder,
+	// and the reservations of ReserveID
+	waitersMu    sync.Mutex
+	closeWaiters map[uint32][]chan *NotificationClosedSignal
+	eventWaiters map[uint32][]chan NotificationEvent
+	// waiterSince holds when listening started, for IDs with listeners
+	waiterSince map[uint32]time.Time
+	// recentClosed holds closed signals nobody was waiting for, oldest first in recentClosedOrder
+	recentClosed      map[uint32]*NotificationClosedSignal
+	recentClosedOrder []uint32
+	// recentClosedSeq holds the value of closedSeq when each signal in recentClosed was remembered
+	recentClosedSeq map[uint32]uint64
+	closedSeq       uint64
+	// lastReserved counts the IDs handed out by ReserveID
+	lastReserved uint32
+	// reserved holds the IDs reserved and not yet sent with SendWithID
+	reserved map[uint32]bool
+	// reservedFor maps the ID of a notification sent with SendWithID to the ID reserved for it, until it is closed
+	reservedFor map[uint32]uint32
+	// sendsWithID counts calls to SendWithID in flight, which need the events received meanwhile in sentEvents
+	sendsWithID int
+	sentEvents  []NotificationEvent
 }
 
 type logger interface {
@@ -298,6 +929,10 @@ type logger interface {
 // option overrides certain parts of a Notifier
 type option func(*notifier)
 
+// Option is the type of the options of New, for implementing Notifier outside of this package,
+// e.g. for its Clone method.
+type Option = option
+
 // WithLogger sets a new logger func
 func WithLogger(logz logger) option {
 	return func(n *notifier) {
@@ -306,53 +941,256 @@ func WithLogger(logz logger) option {
 }
 
 // WithOnAction sets ActionInvokedHandler handler
+//
+// Handlers run one at a time on the signal delivery loop, so a blocking handler delays all later signals.
+// To handle signals for a single notification without blocking the loop, use WaitForSignal or SignalWaiter,
+// which receive signals in the goroutine of the caller.
 func WithOnAction(h ActionInvokedHandler) option {
 	return func(n *notifier) {
 		n.onAction = h
 	}
 }
 
+// WithSignalContext sets the context passed to the handlers of WithOnActionCtx and WithOnClosedCtx,
+// e.g. to carry a logger or tracer. Defaults to context.Background().
+func WithSignalContext(ctx context.Context) option {
+	return func(n *notifier) {
+		n.signalCtx = ctx
+	}
+}
+
+// WithOnActionCtx sets an ActionInvoked handler receiving the context of WithSignalContext.
+// It replaces the handler of WithOnAction, and runs like it.
+func WithOnActionCtx(h ActionInvokedHandlerCtx) option {
+	return func(n *notifier) {
+		n.onAction = func(s *ActionInvokedSignal) { h(n.signalCtx, s) }
+	}
+}
+
+// WithOnClosedCtx sets a NotificationClosed handler receiving the context of WithSignalContext.
+// It replaces the handler of WithOnClosed, and runs like it.
+func WithOnClosedCtx(h NotificationClosedHandlerCtx) option {
+	return func(n *notifier) {
+		n.onClosed = func(s *NotificationClosedSignal) { h(n.signalCtx, s) }
+	}
+}
+
+// WithActionChannel sends ActionInvoked signals to ch, in addition to the handler of WithOnAction,
+// for consuming signals in a select loop. Signals are sent without blocking: when ch is full,
+// the signal is dropped, a warning is logged, and NotifierStats.DroppedSignalCount is incremented.
+// ch is not closed by the Notifier.
+func WithActionChannel(ch chan<- *ActionInvokedSignal) option {
+	return func(n *notifier) {
+		n.actionCh = ch
+	}
+}
+
+// WithClosedChannel sends NotificationClosed signals to ch, in addition to the handler of WithOnClosed.
+// Like WithActionChannel, signals are dropped when ch is full, and ch is not closed by the Notifier.
+func WithClosedChannel(ch chan<- *NotificationClosedSignal) option {
+	return func(n *notifier) {
+		n.closedCh = ch
+	}
+}
+
+// WithOnPanic calls h with the value recovered from a panic in a signal handler,
+// such as those of WithOnAction and WithOnClosed. Panics in signal handlers are always recovered and logged,
+// so signal delivery continues; h is for further reporting. h must not panic.
+func WithOnPanic(h func(recovered interface{})) option {
+	return func(n *notifier) {
+		n.onPanic = h
+	}
+}
+
+// WithHandlerTimeout stops the signal delivery loop from waiting for a handler set with WithOnAction
+// or WithOnClosed after d, so a stuck handler does not stall delivery of later signals.
+// A warning is logged for handlers not returning within d, and handlers still running after 2*d
+// are counted in NotifierStats.TimedOutHandlerCount.
+//
+// Handlers are not stopped when they time out: they keep running, and may still complete and access
+// shared state while later handlers run. Handlers must therefore be safe for concurrent use.
+// Each handler runs in a goroutine of its own when this option is used.
+func WithHandlerTimeout(d time.Duration) option {
+	return func(n *notifier) {
+		n.handlerTimeout = d
+	}
+}
+
 // WithOnClosed sets NotificationClosed handler
+//
+// Like WithOnAction handlers, it runs on the signal delivery loop.
 func WithOnClosed(h NotificationClosedHandler) option {
 	return func(n *notifier) {
 		n.onClosed = h
 	}
 }
 
+// WithContext sets the context used for dbus calls of the Notifier, such as SendNotification.
+// Methods taking a context of their own, such as SendAndWaitForClose, use that context instead.
+// Cancelling ctx makes further calls fail with the error of ctx, it does not close the Notifier.
+func WithContext(ctx context.Context) option {
+	return func(n *notifier) {
+		n.ctx = ctx
+	}
+}
+
+// WithCallTimeout bounds each dbus call of the Notifier without a context of its own, such as
+// SendNotification, to d. The timeout applies on top of the context of WithContext. d must not be negative.
+// Without this option, or with d of 0, calls are only bounded by the dbus connection.
+func WithCallTimeout(d time.Duration) option {
+	return func(n *notifier) {
+		n.callTimeout = d
+	}
+}
+
+// callContext returns the context for a dbus call without a context of its own.
+func (n *notifier) callContext() (context.Context, context.CancelFunc) {
+	if n.callTimeout > 0 {
+		return context.WithTimeout(n.ctx, n.callTimeout)
+	}
+	return n.ctx, func() {}
+}
+
+// WithDefaultAppName sets the AppName used for notifications sent without one.
+// Notification.AppName always takes precedence, the default is only used when it is empty.
+// See also DefaultAppNameFromBinary.
+func WithDefaultAppName(name string) option {
+	return func(n *notifier) {
+		n.appNameDefault = name
+	}
+}
+
+// DefaultAppNameFromBinary returns the name of the running binary, for use with WithDefaultAppName.
+func DefaultAppNameFromBinary() string {
+	return filepath.Base(os.Args[0])
+}
+
+// WithSignalBufferSize bounds the number of signals buffered while signal handlers are running.
+// When the buffer is full, new signals are dropped, a warning is logged,
+// and NotifierStats.DroppedSignalCount is incremented.
+// Note that a dropped NotificationClosed signal leaves SendAndWaitForClose and
+// CloseNotificationSync waiting until their context is done.
+//
+// Without this option no signals are dropped: signals arriving while the default buffer
+// of 10 is full are held by dbus until the handlers catch up.
+func WithSignalBufferSize(size int) option {
+	return func(n *notifier) {
+		n.signalBufferSize = size
+		n.dropSignals = true
+	}
+}
+
 // New creates a new Notifier using conn.
 // See also: Notifier
 func New(conn *dbus.Conn, opts ...option) (Notifier, error) {
+	n := newNotifier(opts...)
+	if err := n.start(conn); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// newNotifier creates a notifier with defaults, overridden by opts.
+func newNotifier(opts ...option) *notifier {
 	n := &notifier{
-		conn:     conn,
-		signal:   make(chan *dbus.Signal, channelBufferSize),
 		onClosed: func(s *NotificationClosedSignal) {},
 		onAction: func(s *ActionInvokedSignal) {},
 		log:      &loggerWrapper{"notify: "},
-		group:    newGroup(),
+		group:    loop.NewGroup(),
+		endpoint: defaultEndpoint,
+		stats:    &notifierStats{},
+		ctx:      context.Background(),
+
+		signalCtx: context.Background(),
+		capsCache: &ttlCache{},
+
+		signalBufferSize: channelBufferSize,
+
+		opts: opts,
+
+		closeWaiters:    map[uint32][]chan *NotificationClosedSignal{},
+		eventWaiters:    map[uint32][]chan NotificationEvent{},
+		waiterSince:     map[uint32]time.Time{},
+		recentClosed:    map[uint32]*NotificationClosedSignal{},
+		recentClosedSeq: map[uint32]uint64{},
+		reserved:        map[uint32]bool{},
+		reservedFor:     map[uint32]uint32{},
 	}
 
 	for _, val := range opts {
 		val(n)
 	}
+	if n.history != nil {
+		n.history.maxMemory = n.historyMaxMemory
+		n.history.onEvict = n.onEvict
+	}
+	if n.signalBufferSize > 0 {
+		n.signal = make(chan *dbus.Signal, n.signalBufferSize)
+		n.intake = n.signal
+		if n.dropSignals {
+			// unbuffered, so only signal holds buffered signals
+			n.intake = make(chan *dbus.Signal)
+		}
+	}
+	return n
+}
+
+// start registers for signals on conn and starts the event loop.
+func (n *notifier) start(conn *dbus.Conn) error {
+	n.conn = conn
+
+	if err := n.endpoint.validate(); err != nil {
+		return err
+	}
+	if n.signalBufferSize < 1 {
+		return fmt.Errorf("invalid signal buffer size: %d", n.signalBufferSize)
+	}
+	if n.bodySplitMax < 0 {
+		return fmt.Errorf("invalid body split size: %d", n.bodySplitMax)
+	}
+	if n.callTimeout < 0 {
+		return fmt.Errorf("invalid call timeout: %v", n.callTimeout)
+	}
+	if n.listenerTTL < 0 {
+		return fmt.Errorf("invalid listener TTL: %v", n.listenerTTL)
+	}
+	if err := n.validateHistory(); err != nil {
+		return err
+	}
 
 	// add a listener (matcher) in dbus for signals to Notification interface.
 	err := n.conn.AddMatchSignal(
-		dbus.WithMatchObjectPath(dbusObjectPath),
-		dbus.WithMatchInterface(dbusNotificationsInterface),
+		dbus.WithMatchObjectPath(n.endpoint.path),
+		dbus.WithMatchInterface(n.endpoint.iface),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error registering for signals in dbus: %w", err)
+		return fmt.Errorf("error registering for signals in dbus: %w", err)
+	}
+	if n.onDaemonRestart != nil {
+		if err := n.conn.AddMatchSignal(n.nameOwnerMatch()...); err != nil {
+			return fmt.Errorf("error registering for name owner changes in dbus: %w", err)
+		}
 	}
 	// register in dbus for signal delivery
-	n.conn.Signal(n.signal)
+	n.conn.Signal(n.intake)
 
 	// start eventloop
-	n.group.Go(n.eventLoop)
+	if n.dropSignals {
+		n.group.Start(n.relaySignals)
+	}
+	n.group.Start(n.eventLoop)
+	if n.listenerTTL > 0 {
+		n.group.Start(n.expireWaitersLoop)
+	}
+	if n.history != nil && n.historyTTL > 0 {
+		n.group.Start(n.evictHistoryLoop)
+	}
 
-	return n, nil
+	return nil
 }
 
 func (n *notifier) eventLoop(done <-chan struct{}) {
+	n.loopGoroutine.Store(goroutineID())
 	for {
 		select {
 		case signal, ok := <-n.signal:
@@ -368,34 +1206,173 @@ func (n *notifier) eventLoop(done <-chan struct{}) {
 	}
 }
 
+// relaySignals moves signals from intake to the signal buffer without blocking,
+// dropping them if the buffer is full. Only used with WithSignalBufferSize.
+// dbus never drops signals, but spawns a goroutine per signal when intake is full,
+// so a slow handler would otherwise pile up goroutines without bound.
+func (n *notifier) relaySignals(done <-chan struct{}) {
+	defer close(n.signal)
+	for {
+		select {
+		case signal, ok := <-n.intake:
+			if !ok {
+				return
+			}
+			select {
+			case n.signal <- signal:
+			default:
+				n.dropSignal(signal.Name)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// dropSignal counts and logs a signal dropped because a buffer was full.
+func (n *notifier) dropSignal(name string) {
+	atomic.AddUint64(&n.stats.droppedSignals, 1)
+	n.log.Printf("Signal buffer full, dropping signal: %v", name)
+}
+
+// runHandler calls h, giving up waiting for it after n.handlerTimeout if set.
+// A panic in h is recovered, see recoverHandler.
+func (n *notifier) runHandler(signalName string, h func()) {
+	if n.handlerTimeout <= 0 {
+		n.recoverHandler(signalName, h)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.recoverHandler(signalName, h)
+	}()
+
+	timer := time.NewTimer(n.handlerTimeout)
+	select {
+	case <-done:
+		timer.Stop()
+		return
+	case <-timer.C:
+	}
+	n.log.Printf("%s handler did not return within %v, continuing", signalName, n.handlerTimeout)
+
+	// count the handler as timed out if it still runs after twice the timeout
+	go func() {
+		timer := time.NewTimer(n.handlerTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			atomic.AddUint64(&n.stats.timedOut, 1)
+		}
+	}()
+}
+
+// goroutineID returns the ID of the calling goroutine, as printed in its stack trace.
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// the trace starts with "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// recoverHandler calls h, recovering from a panic in h so signal delivery continues.
+// The panic is logged with its stack trace, counted in NotifierStats.PanicCount, and passed to the
+// handler of WithOnPanic.
+func (n *notifier) recoverHandler(signalName string, h func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(&n.stats.panics, 1)
+			n.log.Printf("%s handler panicked: %v\n%s", signalName, r, debug.Stack())
+			if n.onPanic != nil {
+				n.onPanic(r)
+			}
+		}
+	}()
+	h()
+}
+
 // signal handler that translates and sends notifications to channels
 func (n *notifier) handleSignal(signal *dbus.Signal) {
 	if signal == nil {
 		return
 	}
 	switch signal.Name {
-	case signalNotificationClosed:
+	case n.endpoint.member(signalNotificationClosed):
+		atomic.AddUint64(&n.stats.closedSignals, 1)
 		nc := &NotificationClosedSignal{
 			ID:     signal.Body[0].(uint32),
 			Reason: Reason(signal.Body[1].(uint32)),
 		}
-		n.onClosed(nc)
-	case signalActionInvoked:
+		now := time.Now()
+		n.history.closed(nc, now)
+		n.runHandler(signalNotificationClosed, func() { n.onClosed(nc) })
+		if n.closedCh != nil {
+			select {
+			case n.closedCh <- nc:
+			default:
+				n.dropSignal(signal.Name)
+			}
+		}
+		n.deliverClosed(nc)
+		n.writeEvent(NotificationEvent{ID: nc.ID, Closed: nc}, now)
+	case n.endpoint.member(signalActionInvoked):
+		atomic.AddUint64(&n.stats.actionSignals, 1)
 		is := &ActionInvokedSignal{
-			ID:        signal.Body[0].(uint32),
+			ID:        signalNotificationID(signal.Body[0]),
 			ActionKey: signal.Body[1].(string),
 		}
-		n.onAction(is)
+		n.runHandler(signalActionInvoked, func() { n.onAction(is) })
+		if n.actionCh != nil {
+			select {
+			case n.actionCh <- is:
+			default:
+				n.dropSignal(signal.Name)
+			}
+		}
+		n.deliverAction(is)
+		n.writeEvent(NotificationEvent{ID: is.ID, Action: is}, time.Now())
+	case dbusNameOwnerChanged:
+		n.handleNameOwnerChanged(signal)
 	default:
+		atomic.AddUint64(&n.stats.unknownSignals, 1)
 		n.log.Printf("Received unknown signal: %+v", signal)
 	}
 }
 
 func (n *notifier) GetCapabilities() ([]string, error) {
-	return GetCapabilities(n.conn)
+	if n.portal {
+		return []string{}, fmt.Errorf("%w: using the notification portal", ErrGetCapabilitiesNotSupported)
+	}
+	ctx, cancel := n.callContext()
+	defer cancel()
+	return getCapabilities(ctx, n.conn, n.endpoint)
 }
 func (n *notifier) GetServerInformation() (ServerInformation, error) {
-	return GetServerInformation(n.conn)
+	ctx, cancel := n.callContext()
+	defer cancel()
+	get := func() (ServerInformation, error) {
+		if n.portal {
+			return getPortalServerInformation(n.conn, n.endpoint)
+		}
+		return getServerInformation(ctx, n.conn, n.endpoint)
+	}
+	if n.infoCache == nil {
+		return get()
+	}
+	info, err := n.infoCache.get(func() (interface{}, error) {
+		return get()
+	})
+	if err != nil {
+		return ServerInformation{}, err
+	}
+	return info.(ServerInformation), nil
 }
 
 // SendNotification sends a notification to the notification server and returns the ID or an error.
@@ -430,8 +1407,73 @@ func (n *notifier) GetServerInformation() (ServerInformation, error) {
 // The returned ID is always greater than zero. Servers must make sure not to return zero as an ID.
 //
 // If replaces_id is not 0, the returned value is the same value as replaces_id.
+//
+// With WithBodySplitting, a long body is sent as several notifications, and the ID of the first is returned.
 func (n *notifier) SendNotification(note Notification) (uint32, error) {
-	return SendNotification(n.conn, note)
+	if n.splits(note) {
+		ids, err := n.SendNotificationSplit(note)
+		if len(ids) == 0 {
+			return 0, err
+		}
+		return ids[0], err
+	}
+	ctx, cancel := n.callContext()
+	defer cancel()
+	return n.sendNotification(ctx, note)
+}
+
+// sendNotification sends note like SendNotification, with the dbus call bound to ctx.
+func (n *notifier) sendNotification(ctx context.Context, note Notification) (uint32, error) {
+	if !n.IsOpen() {
+		return 0, ErrNotifierClosed
+	}
+	atomic.AddUint64(&n.stats.send, 1)
+	note = n.prepare(ctx, note)
+	if n.maxNotificationSize > 0 {
+		if size := EstimateWireSize(note); size > n.maxNotificationSize {
+			atomic.AddUint64(&n.stats.sendError, 1)
+			return 0, &NotificationTooLargeError{Estimated: size, Max: n.maxNotificationSize}
+		}
+	}
+	n.waitersMu.Lock()
+	closedSeq := n.closedSeq
+	n.waitersMu.Unlock()
+
+	var id uint32
+	var err error
+	if n.portal {
+		id, err = sendPortalNotification(ctx, n.conn, n.endpoint, note)
+	} else {
+		id, err = sendNotification(ctx, n.conn, n.endpoint, note)
+	}
+	if err != nil {
+		atomic.AddUint64(&n.stats.sendError, 1)
+		return id, err
+	}
+	// a signal remembered for an earlier notification with the same ID does not belong to this one
+	n.forgetStaleClosed(id, closedSeq)
+	n.history.add(id, note, time.Now())
+	return id, nil
+}
+
+// prepare applies the options of n that alter notifications before they are sent.
+func (n *notifier) prepare(ctx context.Context, note Notification) Notification {
+	if note.AppName == "" {
+		note.AppName = n.appNameDefault
+	}
+	if note.AppIcon == "" && n.iconCache != nil {
+		note.AppIcon = n.resolveAppIcon(ctx, note.AppName)
+	}
+	if note.AppIcon == "" {
+		note.AppIcon = n.appIconDefault
+	}
+	if len(n.actionTooltips) > 0 {
+		note = addActionTooltips(note.Clone(), n.actionTooltips)
+	}
+	if n.autoEscapeBody {
+		note = n.escapeBody(note)
+	}
+	return note
 }
 
 // CloseNotification causes a notification to be forcefully closed and removed from the user's view.
@@ -440,13 +1482,32 @@ func (n *notifier) SendNotification(note Notification) (uint32, error) {
 //
 // The NotificationClosed (dbus) signal is emitted by this method.
 // If the notification no longer exists, an empty D-BUS Error message is sent back.
-func (n *notifier) CloseNotification(id uint32) (bool, error) {
-	obj := n.conn.Object(dbusNotificationsInterface, dbusObjectPath)
-	call := obj.Call(callCloseNotification, 0, id)
-	if call.Err != nil {
-		return false, call.Err
+// When the server reports the ID as invalid, a *NotificationNotFoundError is returned, see IsNotFound.
+func (n *notifier) CloseNotification(id uint32) error {
+	ctx, cancel := n.callContext()
+	defer cancel()
+	return n.closeNotification(ctx, id)
+}
+
+// closeNotification closes the notification with id like CloseNotification, with the dbus call bound to ctx.
+func (n *notifier) closeNotification(ctx context.Context, id uint32) error {
+	if id == 0 {
+		return ErrInvalidNotificationID
+	}
+	if !n.IsOpen() {
+		return ErrNotifierClosed
 	}
-	return true, nil
+	atomic.AddUint64(&n.stats.close, 1)
+	var err error
+	if n.portal {
+		err = removePortalNotification(ctx, n.conn, n.endpoint, id)
+	} else {
+		err = closeNotification(ctx, n.conn, n.endpoint, id)
+	}
+	if err != nil {
+		atomic.AddUint64(&n.stats.closeError, 1)
+	}
+	return err
 }
 
 // NotificationClosedSignal holds data for *Closed callbacks from Notifications Interface.
@@ -490,58 +1551,61 @@ func (r Reason) String() string {
 }
 
 // Close cleans up and shuts down signal delivery loop. It is safe to be called
-// multiple times.
+// multiple times. It is equivalent to Shutdown(context.Background()).
 func (n *notifier) Close() error {
-	return n.group.Close(func() error {
-		// remove signal reception
-		n.conn.RemoveSignal(n.signal)
-
-		// unregister in dbus:
-		return n.conn.RemoveMatchSignal(
-			dbus.WithMatchObjectPath(dbusObjectPath),
-			dbus.WithMatchInterface(dbusNotificationsInterface),
-		)
-	})
+	return n.Shutdown(context.Background())
 }
 
-type loggerWrapper struct {
-	prefix string
+// IsOpen returns true until Close or Shutdown is called.
+// Once closed, SendNotification and CloseNotification fail with ErrNotifierClosed without calling dbus.
+func (n *notifier) IsOpen() bool {
+	return atomic.LoadInt32(&n.closed) == 0
 }
 
-func (l *loggerWrapper) Printf(format string, v ...interface{}) {
-	log.Printf(l.prefix+format, v...)
+// Shutdown stops the signal delivery loop and waits for running signal handlers to return,
+// then cleans up like Close. If ctx is done before the handlers return, signal delivery is
+// stopped without waiting for them, and the error of ctx is returned.
+// It is safe to be called multiple times, and together with Close.
+//
+// When called from within a signal handler, it does not wait for signal delivery to stop,
+// as the handler runs on the signal delivery loop and would wait for itself.
+func (n *notifier) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&n.closed, 1)
+	if id, ok := n.loopGoroutine.Load().(uint64); ok && id == goroutineID() {
+		return n.group.StopNoWait(n.cleanup)
+	}
+	return n.group.StopWithContext(ctx, n.cleanup)
 }
 
-// group abstracts away shutdown logic for the event loop.
-type group struct {
-	wg        sync.WaitGroup
-	closeOnce sync.Once
-	done      chan struct{}
-	err       error
-}
+// cleanup unregisters n from dbus once signal delivery is stopped, and closes the connection if owned by n.
+func (n *notifier) cleanup() error {
+	// remove signal reception
+	n.conn.RemoveSignal(n.intake)
 
-func newGroup() *group {
-	return &group{
-		done: make(chan struct{}),
+	// unregister in dbus:
+	err := n.conn.RemoveMatchSignal(
+		dbus.WithMatchObjectPath(n.endpoint.path),
+		dbus.WithMatchInterface(n.endpoint.iface),
+	)
+	if n.onDaemonRestart != nil {
+		if matchErr := n.conn.RemoveMatchSignal(n.nameOwnerMatch()...); err == nil {
+			err = matchErr
+		}
+	}
+
+	// only close connections we created ourselves
+	if n.ownsConn {
+		if closeErr := n.conn.Close(); err == nil {
+			err = closeErr
+		}
 	}
+	return err
 }
 
-// Go runs f in a new goroutine. done is closed as a signal for f to shut down.
-// g.Close waits for f to finish before returning.
-func (g *group) Go(f func(done <-chan struct{})) {
-	g.wg.Add(1)
-	defer g.wg.Done()
-	go f(g.done)
+type loggerWrapper struct {
+	prefix string
 }
 
-// Close signals all goroutines started by g to shut down and waits for them to
-// finish. It then calls f for further clean up. It is safe to be called
-// multiple times.
-func (g *group) Close(f func() error) error {
-	g.closeOnce.Do(func() {
-		close(g.done)
-		g.wg.Wait()
-		g.err = f()
-	})
-	return g.err
+func (l *loggerWrapper) Printf(format string, v ...interface{}) {
+	log.Printf(l.prefix+format, v...)
 }
@@ -1,8 +1,12 @@
 package notify
 
 import (
-	"github.com/stretchr/testify/require"
+	"image"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestExpiration(t *testing.T) {
@@ -14,3 +18,340 @@ func TestExpiration(t *testing.T) {
 	n.ExpireTimeout = ExpireTimeoutNever
 	n.ExpireTimeout = ExpireTimeoutSetByNotificationServer
 }
+
+func TestExpirePredicates(t *testing.T) {
+	require.True(t, IsExpireNever(ExpireTimeoutNever))
+	require.True(t, IsExpireNever(Notification{}.ExpireTimeout))
+	require.False(t, IsExpireNever(ExpireTimeoutSetByNotificationServer))
+	require.False(t, IsExpireNever(time.Second))
+
+	require.True(t, IsExpireServerDefault(ExpireTimeoutSetByNotificationServer))
+	require.False(t, IsExpireServerDefault(ExpireTimeoutNever))
+	require.False(t, IsExpireServerDefault(-time.Second))
+}
+
+func TestTruncateBody(t *testing.T) {
+	n := &Notification{Body: "hello world"}
+	n.TruncateBody(8, "...")
+	require.Equal(t, "hello...", n.Body)
+
+	n = &Notification{Body: "short"}
+	n.TruncateBody(8, "...")
+	require.Equal(t, "short", n.Body)
+
+	// "æøå" is 6 bytes, must not be cut in the middle of a rune
+	n = &Notification{Body: "æøå"}
+	n.TruncateBody(4, "")
+	require.Equal(t, "æø", n.Body)
+	n.TruncateBody(3, "")
+	require.Equal(t, "æ", n.Body)
+
+	n = &Notification{Summary: "ab日本語"}
+	n.TruncateSummary(7, "…")
+	require.Equal(t, "ab…", n.Summary)
+}
+
+func TestTruncateBodyDefault(t *testing.T) {
+	body := make([]byte, 2000)
+	for i := range body {
+		body[i] = 'a'
+	}
+	n := TruncateBodyDefault(&Notification{Body: string(body)})
+	require.Len(t, n.Body, 1024)
+	require.True(t, strings.HasSuffix(n.Body, "…"))
+}
+
+func TestAppendBody(t *testing.T) {
+	defer func(body int) { maxBodyBytes = body }(maxBodyBytes)
+	maxBodyBytes = 16
+
+	n := Notification{}
+	require.NoError(t, n.AppendBody("one"))
+	require.NoError(t, n.AppendBody(EscapeMarkup("<b>")))
+	require.Equal(t, "one\n&lt;b&gt;", n.Body)
+	require.Equal(t, ErrBodyTooLong, n.AppendBody("too long"))
+	require.Equal(t, "one\n&lt;b&gt;", n.Body)
+
+	n = Notification{}
+	require.NoError(t, n.AppendBodyLine("a"))
+	require.NoError(t, n.AppendBodyLine("b"))
+	require.Equal(t, "a\nb\n", n.Body)
+}
+
+func TestUrgencyIsKnown(t *testing.T) {
+	for _, u := range AllUrgencies() {
+		require.True(t, u.IsKnown(), "urgency %d", u)
+	}
+	require.Len(t, AllUrgencies(), 3)
+	require.False(t, Urgency(3).IsKnown())
+	require.False(t, Urgency(255).IsKnown())
+}
+
+func TestMergeFrom(t *testing.T) {
+	base := Notification{
+		AppName:       "app",
+		AppIcon:       "mail-unread",
+		Summary:       "base summary",
+		Actions:       []Action{{Key: "open", Label: "Open"}},
+		ExpireTimeout: 5 * time.Second,
+	}
+	base.AddHint(HintSoundWithName("bell"))
+	base.SetUrgency(UrgencyLow)
+
+	override := Notification{Summary: "event", Body: "happened"}
+	override.SetUrgency(UrgencyCritical)
+
+	merged := base.MergeFrom(override)
+	require.Equal(t, "app", merged.AppName)
+	require.Equal(t, "mail-unread", merged.AppIcon)
+	require.Equal(t, "event", merged.Summary)
+	require.Equal(t, "happened", merged.Body)
+	require.Equal(t, base.Actions, merged.Actions)
+	require.Equal(t, 5*time.Second, merged.ExpireTimeout)
+	require.Equal(t, byte(UrgencyCritical), merged.Hints["urgency"].Value())
+	require.Equal(t, "bell", merged.Hints["sound-name"].Value())
+
+	// base is left untouched
+	require.Equal(t, byte(UrgencyLow), base.Hints["urgency"].Value())
+
+	merged = base.MergeFrom(Notification{Actions: []Action{}})
+	require.Empty(t, merged.Actions)
+}
+
+func TestActionsMap(t *testing.T) {
+	n := Notification{
+		Actions: []Action{
+			{Key: "open", Label: "Open"},
+			{Key: "cancel", Label: "Cancel"},
+			{Key: "open", Label: "Open again"},
+		},
+	}
+	m := n.ActionsMap()
+	require.Len(t, m, 2)
+	require.Equal(t, "Open again", m["open"].Label)
+	require.Equal(t, "Cancel", m["cancel"].Label)
+
+	require.Equal(t, []string{"open", "cancel", "open"}, n.ActionKeys())
+	require.Equal(t, []string{"Open", "Cancel", "Open again"}, n.ActionLabels())
+	require.Len(t, n.Actions, 3)
+
+	require.Empty(t, Notification{}.ActionsMap())
+}
+
+func TestActionsFromOrderedMap(t *testing.T) {
+	labels := map[string]string{"open": "Open", "cancel": "Cancel", "snooze": "Snooze"}
+
+	actions, err := ActionsFromOrderedMap([]string{"snooze", "open", "cancel"}, labels)
+	require.NoError(t, err)
+	require.Equal(t, []Action{
+		{Key: "snooze", Label: "Snooze"},
+		{Key: "open", Label: "Open"},
+		{Key: "cancel", Label: "Cancel"},
+	}, actions)
+
+	_, err = ActionsFromOrderedMap([]string{"open", "missing"}, labels)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing")
+}
+
+func TestHintImageDataRGBASubImage(t *testing.T) {
+	parent := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range parent.Pix {
+		parent.Pix[i] = byte(i)
+	}
+	sub := parent.SubImage(image.Rect(2, 2, 6, 6)).(*image.RGBA)
+
+	var expected []byte
+	for y := 2; y < 6; y++ {
+		for x := 2; x < 6; x++ {
+			offset := parent.PixOffset(x, y)
+			expected = append(expected, parent.Pix[offset:offset+4]...)
+		}
+	}
+
+	hint := HintImageDataRGBA(sub)
+	data := hint.Variant.Value().(ImageData)
+	require.EqualValues(t, 4, data.Width)
+	require.EqualValues(t, 4, data.Height)
+	require.EqualValues(t, 16, data.RowStride)
+	require.Equal(t, expected, data.Data)
+
+	full := HintImageDataRGBA(parent).Variant.Value().(ImageData)
+	require.Equal(t, parent.Pix, full.Data)
+}
+
+func TestImageDataRowStride(t *testing.T) {
+	parent := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	sub := parent.SubImage(image.Rect(10, 10, 60, 40)).(*image.RGBA)
+
+	full := FromRGBA(parent)
+	require.EqualValues(t, 400, full.RowStride)
+	require.NoError(t, full.Valid())
+
+	cropped := FromRGBA(sub)
+	require.EqualValues(t, 200, cropped.RowStride)
+	require.Len(t, cropped.Data, 200*30)
+	require.NoError(t, cropped.Valid())
+
+	// padded rows are allowed, and the last row needs no padding
+	padded := ImageData{Width: 2, Height: 2, RowStride: 12, HasAlpha: true, BitsPerSample: 8, Channels: 4, Data: make([]byte, 12+8)}
+	require.NoError(t, padded.Valid())
+	padded.RowStride = 7
+	require.Error(t, padded.Valid())
+}
+
+func TestWithReplace(t *testing.T) {
+	original := Notification{
+		Summary: "original",
+		Actions: []Action{{Key: "open", Label: "Open"}},
+	}
+	original.SetUrgency(UrgencyLow)
+
+	updated := original.WithReplace(42)
+	require.EqualValues(t, 42, updated.ReplacesID)
+	require.Equal(t, "original", updated.Summary)
+
+	updated.Summary = "updated"
+	updated.Actions[0].Label = "Changed"
+	updated.SetUrgency(UrgencyCritical)
+
+	require.Zero(t, original.ReplacesID)
+	require.Equal(t, "original", original.Summary)
+	require.Equal(t, "Open", original.Actions[0].Label)
+	require.Equal(t, byte(UrgencyLow), original.Hints["urgency"].Value())
+}
+
+func TestActionInvokedSignalMatches(t *testing.T) {
+	open := Action{Key: "open", Label: "Open"}
+	cancel := Action{Key: "cancel", Label: "Cancel"}
+	s := &ActionInvokedSignal{ID: 1, ActionKey: "open"}
+
+	require.True(t, s.MatchesAction(open))
+	require.False(t, s.MatchesAction(cancel))
+	require.True(t, s.MatchesAnyAction([]Action{cancel, open}))
+	require.False(t, s.MatchesAnyAction([]Action{cancel}))
+
+	label, ok := s.ActionLabel([]Action{cancel, open})
+	require.True(t, ok)
+	require.Equal(t, "Open", label)
+	_, ok = s.ActionLabel(nil)
+	require.False(t, ok)
+
+	a, ok := s.FindAction([]Action{cancel, open})
+	require.True(t, ok)
+	require.Equal(t, open, a)
+	_, ok = s.FindAction([]Action{cancel})
+	require.False(t, ok)
+}
+
+func TestNotificationFindAction(t *testing.T) {
+	n := Notification{Actions: []Action{{Key: "open", Label: "Open"}, {Key: "cancel", Label: "Cancel"}}}
+
+	a, ok := n.FindAction("cancel")
+	require.True(t, ok)
+	require.Equal(t, Action{Key: "cancel", Label: "Cancel"}, a)
+
+	a, ok = n.FindAction("missing")
+	require.False(t, ok)
+	require.Zero(t, a)
+}
+
+func TestDefaultAction(t *testing.T) {
+	n := Notification{Actions: []Action{{Key: "open", Label: "Open"}}}
+	n.SetDefaultAction("Show")
+	require.Equal(t, []Action{{Key: "open", Label: "Open"}, {Key: ActionKeyDefault, Label: "Show"}}, n.Actions)
+
+	// an existing default action is replaced
+	n.SetDefaultAction("View")
+	require.Len(t, n.Actions, 2)
+	require.Equal(t, "View", n.Actions[1].Label)
+
+	require.True(t, (&ActionInvokedSignal{ActionKey: ActionKeyDefault}).IsDefault())
+	require.False(t, (&ActionInvokedSignal{ActionKey: "open"}).IsDefault())
+}
+
+func TestImageDataValid(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	data := FromRGBA(img)
+	require.NoError(t, data.Valid())
+	require.Equal(t, "(iiibiiay)", data.ToVariant().Signature().String())
+
+	broken := data
+	broken.RowStride = 16
+	require.Error(t, broken.Valid())
+
+	broken = data
+	broken.Data = broken.Data[:len(broken.Data)-1]
+	require.Error(t, broken.Valid())
+
+	broken = data
+	broken.Channels = 0
+	broken.Data = nil
+	require.Error(t, broken.Valid())
+
+	broken = data
+	broken.HasAlpha = false
+	require.Error(t, broken.Valid())
+
+	rgb := ImageData{Width: 2, Height: 1, RowStride: 6, BitsPerSample: 8, Channels: 3, Data: make([]byte, 6)}
+	require.NoError(t, rgb.Valid())
+
+	// width*channels does not fit in int32
+	huge := ImageData{Width: 1 << 30, Height: 1, RowStride: 1 << 30, HasAlpha: true, BitsPerSample: 8, Channels: 4}
+	require.Error(t, huge.Valid())
+
+	require.Error(t, ImageData{}.Valid())
+}
+
+func TestGetUrgency(t *testing.T) {
+	urgency, ok := GetUrgency(Notification{})
+	require.False(t, ok)
+	require.Equal(t, UrgencyNormal, urgency)
+
+	for _, u := range []Urgency{UrgencyLow, UrgencyNormal, UrgencyCritical} {
+		n := Notification{}
+		n.SetUrgency(u)
+		urgency, ok := GetUrgency(n)
+		require.True(t, ok)
+		require.Equal(t, u, urgency)
+	}
+}
+
+func TestUrgencyField(t *testing.T) {
+	n := Notification{}
+	n.SetUrgency(UrgencyLow)
+	n.Urgency = UrgencyCritical.Ptr()
+
+	urgency, ok := GetUrgency(n)
+	require.True(t, ok)
+	require.Equal(t, UrgencyCritical, urgency)
+
+	clone := n.Clone()
+	*clone.Urgency = UrgencyNormal
+	require.Equal(t, UrgencyCritical, *n.Urgency)
+
+	merged := Notification{}.MergeFrom(n)
+	require.Equal(t, UrgencyCritical, *merged.Urgency)
+
+	n.Urgency = Urgency(9).Ptr()
+	require.Error(t, n.Validate())
+}
+
+func TestHintHelpersNilHints(t *testing.T) {
+	n := Notification{}
+	require.Nil(t, n.Hints)
+	n.SetUrgency(UrgencyCritical)
+	require.Equal(t, byte(UrgencyCritical), n.Hints["urgency"].Value())
+
+	n = Notification{}
+	n.AddHint(HintImageFilePath("/tmp/image.png"))
+	require.Len(t, n.Hints, 1)
+
+	n = Notification{}
+	n.ApplyHints(HintSoundWithName("bell"), HintFromCategory(CategoryIM))
+	require.Len(t, n.Hints, 2)
+
+	n = Notification{}
+	NewHintSet(HintSoundWithName("bell")).ApplyTo(&n)
+	require.Len(t, n.Hints, 1)
+}
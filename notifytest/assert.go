@@ -0,0 +1,75 @@
+package notifytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/esiqveland/notify"
+)
+
+// AssertActionInvoked checks that mock emits the ActionInvoked signal for notifID with actionKey
+// within mock.SignalTimeout, and fails the test with t.Errorf otherwise. Returns whether the check passed.
+func AssertActionInvoked(t testing.TB, mock *MockNotifier, notifID uint32, actionKey string) bool {
+	t.Helper()
+	if waitForSignal(mock, func(e notify.NotificationEvent) bool {
+		return e.ID == notifID && e.Action != nil && e.Action.ActionKey == actionKey
+	}) {
+		return true
+	}
+	t.Errorf("notifytest: no ActionInvoked signal for notification %d with key %q within %v, got signals: %v",
+		notifID, actionKey, mock.signalTimeout(), formatSignals(mock.Signals()))
+	return false
+}
+
+// AssertNotificationClosed checks that mock emits the NotificationClosed signal for notifID with reason
+// within mock.SignalTimeout, and fails the test with t.Errorf otherwise. Returns whether the check passed.
+func AssertNotificationClosed(t testing.TB, mock *MockNotifier, notifID uint32, reason notify.Reason) bool {
+	t.Helper()
+	if waitForSignal(mock, func(e notify.NotificationEvent) bool {
+		return e.ID == notifID && e.Closed != nil && e.Closed.Reason == reason
+	}) {
+		return true
+	}
+	t.Errorf("notifytest: no NotificationClosed signal for notification %d with reason %v within %v, got signals: %v",
+		notifID, reason, mock.signalTimeout(), formatSignals(mock.Signals()))
+	return false
+}
+
+// RequireNotificationSent checks that a notification with expectedSummary was sent through mock,
+// and stops the test with t.Fatalf otherwise. Returns the first notification sent with expectedSummary.
+func RequireNotificationSent(t testing.TB, mock *MockNotifier, expectedSummary string) notify.Notification {
+	t.Helper()
+	sent := mock.SentNotifications()
+	for _, n := range sent {
+		if n.Summary == expectedSummary {
+			return n
+		}
+	}
+	summaries := make([]string, 0, len(sent))
+	for _, n := range sent {
+		summaries = append(summaries, n.Summary)
+	}
+	t.Fatalf("notifytest: no notification with summary %q was sent, got summaries: %q", expectedSummary, summaries)
+	return notify.Notification{}
+}
+
+// waitForSignal waits for any signal of mock matching match, emitted before or during the wait.
+func waitForSignal(mock *MockNotifier, match func(notify.NotificationEvent) bool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), mock.signalTimeout())
+	defer cancel()
+	_, ok := mock.waitForSignal(ctx, 0, match)
+	return ok
+}
+
+// formatSignals formats signals for failure messages.
+func formatSignals(signals []notify.NotificationEvent) []string {
+	out := make([]string, 0, len(signals))
+	for _, e := range signals {
+		if e.Closed != nil {
+			out = append(out, e.Closed.String())
+		} else if e.Action != nil {
+			out = append(out, e.Action.String())
+		}
+	}
+	return out
+}
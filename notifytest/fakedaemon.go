@@ -0,0 +1,355 @@
+// Package notifytest provides utilities for testing code that uses package notify
+// without a running desktop session.
+package notifytest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/esiqveland/notify"
+)
+
+const (
+	dbusObjectPath             = "/org/freedesktop/Notifications"
+	dbusNotificationsInterface = "org.freedesktop.Notifications"
+	signalNotificationClosed   = "org.freedesktop.Notifications.NotificationClosed"
+	signalActionInvoked        = "org.freedesktop.Notifications.ActionInvoked"
+	errorInvalidID             = "org.freedesktop.Notifications.InvalidId"
+	errorUnknownMethod         = "org.freedesktop.DBus.Error.UnknownMethod"
+
+	busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:tmpdir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+)
+
+// ErrNoDBusDaemon is returned by NewFakeDaemon when the dbus-daemon binary can not be found.
+// Tests will usually want to skip when they see this error.
+var ErrNoDBusDaemon = errors.New("notifytest: dbus-daemon not found in PATH")
+
+// FakeDaemon is a fake notification server running on its own private message bus.
+// It records every notification it receives, and can emit signals on request.
+//
+// Connect to the bus of the daemon using FakeDaemon.Connect().
+type FakeDaemon struct {
+	cmd     *exec.Cmd
+	tmpDir  string
+	address string
+	conn    *dbus.Conn
+
+	mu           sync.Mutex
+	lastID       uint32
+	sent         []notify.Notification
+	open         map[uint32]bool
+	capabilities []string
+	noCaps       bool
+	// instantAction is emitted as ActionInvoked before replying to Notify, unless empty
+	instantAction string
+	info          notify.ServerInformation
+}
+
+// NewFakeDaemon starts a private dbus-daemon and registers a fake notification server
+// at org.freedesktop.Notifications on it.
+// Caller is responsible for calling Close() to shut down the daemon.
+func NewFakeDaemon() (*FakeDaemon, error) {
+	bin, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		return nil, ErrNoDBusDaemon
+	}
+	tmpDir, err := ioutil.TempDir("", "notifytest")
+	if err != nil {
+		return nil, err
+	}
+	d := &FakeDaemon{
+		tmpDir:       tmpDir,
+		open:         map[uint32]bool{},
+		capabilities: []string{"actions", "body", "body-markup", "icon-static", "sound"},
+		info: notify.ServerInformation{
+			Name:        "notifytest",
+			Vendor:      "notify",
+			Version:     "1.0.0",
+			SpecVersion: "1.2",
+		},
+	}
+	if err := d.start(bin); err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *FakeDaemon) start(bin string) error {
+	configPath := filepath.Join(d.tmpDir, "bus.conf")
+	config := fmt.Sprintf(busConfig, d.tmpDir)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		return err
+	}
+
+	d.cmd = exec.Command(bin, "--config-file="+configPath, "--nofork", "--nopidfile", "--print-address=1")
+	d.cmd.Stderr = ioutil.Discard
+	stdout, err := d.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := d.cmd.Start(); err != nil {
+		return fmt.Errorf("error starting dbus-daemon: %w", err)
+	}
+
+	address, err := readAddress(stdout)
+	if err != nil {
+		return err
+	}
+	d.address = address
+
+	conn, err := d.register()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+	return nil
+}
+
+// register connects to the bus, and exports the fake server at org.freedesktop.Notifications.
+func (d *FakeDaemon) register() (*dbus.Conn, error) {
+	conn, err := d.Connect()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Export(&server{d: d, conn: conn}, dbusObjectPath, dbusNotificationsInterface); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("error exporting fake notification server: %w", err)
+	}
+	reply, err := conn.RequestName(dbusNotificationsInterface, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("error requesting name %v: %w", dbusNotificationsInterface, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		_ = conn.Close()
+		return nil, fmt.Errorf("could not become primary owner of %v: %v", dbusNotificationsInterface, reply)
+	}
+	return conn, nil
+}
+
+// SimulateRestart disconnects the fake server from the bus and connects it again,
+// like a notification server crashing and being restarted.
+// org.freedesktop.Notifications loses its owner, and then gets a new owner.
+// Recorded notifications are kept, but all notifications are considered closed.
+func (d *FakeDaemon) SimulateRestart() error {
+	d.mu.Lock()
+	old := d.conn
+	d.open = map[uint32]bool{}
+	d.mu.Unlock()
+	_ = old.Close()
+
+	conn, err := d.register()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+	return nil
+}
+
+func readAddress(stdout io.Reader) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	lines := make(chan result, 1)
+	go func() {
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		lines <- result{line, err}
+	}()
+	select {
+	case r := <-lines:
+		if r.err != nil {
+			return "", fmt.Errorf("error reading dbus-daemon address: %w", r.err)
+		}
+		return strings.TrimSpace(r.line), nil
+	case <-time.After(5 * time.Second):
+		return "", errors.New("timed out waiting for dbus-daemon address")
+	}
+}
+
+// Address returns the address of the private message bus.
+func (d *FakeDaemon) Address() string {
+	return d.address
+}
+
+// Connect opens a new authenticated connection to the private message bus of d.
+// Caller is responsible for closing the connection.
+func (d *FakeDaemon) Connect() (*dbus.Conn, error) {
+	conn, err := dbus.Connect(d.address)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to fake daemon: %w", err)
+	}
+	return conn, nil
+}
+
+// SetCapabilities sets the response of GetCapabilities.
+func (d *FakeDaemon) SetCapabilities(capabilities []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.capabilities = append([]string(nil), capabilities...)
+}
+
+// SetCapabilitiesUnsupported makes GetCapabilities fail with an UnknownMethod error when unsupported is true,
+// like servers that do not implement the call.
+func (d *FakeDaemon) SetCapabilitiesUnsupported(unsupported bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.noCaps = unsupported
+}
+
+// SetInstantAction makes the server emit the ActionInvoked signal with key for every notification
+// before replying to Notify, as if the user invoked the action right away. An empty key turns it off.
+func (d *FakeDaemon) SetInstantAction(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.instantAction = key
+}
+
+// SetServerInformation sets the response of GetServerInformation.
+func (d *FakeDaemon) SetServerInformation(info notify.ServerInformation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.info = info
+}
+
+// SentNotifications returns all notifications received by Notify calls, in order.
+func (d *FakeDaemon) SentNotifications() []notify.Notification {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]notify.Notification(nil), d.sent...)
+}
+
+// SimulateClose emits the NotificationClosed signal for id with reason.
+func (d *FakeDaemon) SimulateClose(id uint32, reason notify.Reason) error {
+	d.mu.Lock()
+	delete(d.open, id)
+	conn := d.conn
+	d.mu.Unlock()
+	return conn.Emit(dbusObjectPath, signalNotificationClosed, id, uint32(reason))
+}
+
+// SimulateAction emits the ActionInvoked signal for id with the action key.
+func (d *FakeDaemon) SimulateAction(id uint32, key string) error {
+	d.mu.Lock()
+	conn := d.conn
+	d.mu.Unlock()
+	return conn.Emit(dbusObjectPath, signalActionInvoked, id, key)
+}
+
+// Close shuts down the fake server and its message bus.
+func (d *FakeDaemon) Close() error {
+	d.mu.Lock()
+	conn := d.conn
+	d.mu.Unlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+	if d.cmd != nil && d.cmd.Process != nil {
+		_ = d.cmd.Process.Kill()
+		_ = d.cmd.Wait()
+	}
+	return os.RemoveAll(d.tmpDir)
+}
+
+// server implements the methods of org.freedesktop.Notifications exported on the bus.
+type server struct {
+	d *FakeDaemon
+	// conn is the connection the server is exported on, and emits signals on.
+	conn *dbus.Conn
+}
+
+func (s *server) Notify(
+	appName string,
+	replacesID uint32,
+	appIcon string,
+	summary string,
+	body string,
+	actions []string,
+	hints map[string]dbus.Variant,
+	expireTimeout int32,
+) (uint32, *dbus.Error) {
+	note := notify.Notification{
+		AppName:       appName,
+		ReplacesID:    replacesID,
+		AppIcon:       appIcon,
+		Summary:       summary,
+		Body:          body,
+		Hints:         hints,
+		ExpireTimeout: time.Duration(expireTimeout) * time.Millisecond,
+	}
+	for i := 0; i+1 < len(actions); i += 2 {
+		note.Actions = append(note.Actions, notify.Action{Key: actions[i], Label: actions[i+1]})
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.sent = append(s.d.sent, note)
+
+	id := replacesID
+	if id == 0 || !s.d.open[id] {
+		s.d.lastID++
+		id = s.d.lastID
+	}
+	s.d.open[id] = true
+	if s.d.instantAction != "" {
+		_ = s.conn.Emit(dbusObjectPath, signalActionInvoked, id, s.d.instantAction)
+	}
+	return id, nil
+}
+
+func (s *server) CloseNotification(id uint32) *dbus.Error {
+	s.d.mu.Lock()
+	open := s.d.open[id]
+	delete(s.d.open, id)
+	s.d.mu.Unlock()
+
+	if !open {
+		return dbus.NewError(errorInvalidID, []interface{}{fmt.Sprintf("invalid notification id: %d", id)})
+	}
+	_ = s.conn.Emit(dbusObjectPath, signalNotificationClosed, id, uint32(notify.ReasonClosedByCall))
+	return nil
+}
+
+func (s *server) GetCapabilities() ([]string, *dbus.Error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.noCaps {
+		return nil, dbus.NewError(errorUnknownMethod, []interface{}{"GetCapabilities is not implemented"})
+	}
+	return append([]string{}, s.d.capabilities...), nil
+}
+
+func (s *server) GetServerInformation() (string, string, string, string, *dbus.Error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	info := s.d.info
+	return info.Name, info.Vendor, info.Version, info.SpecVersion, nil
+}
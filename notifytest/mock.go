@@ -0,0 +1,321 @@
+package notifytest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/esiqveland/notify"
+)
+
+// defaultSignalTimeout is how long the assertions wait for a signal, unless MockNotifier.SignalTimeout is set.
+const defaultSignalTimeout = 5 * time.Second
+
+// MockNotifier is an in-memory notify.Notifier for unit tests, which records the notifications sent
+// and the signals emitted, without a message bus. Signals are emitted by CloseNotification,
+// SimulateClose and SimulateAction. It is safe for concurrent use.
+type MockNotifier struct {
+	// SignalTimeout is how long AssertActionInvoked and AssertNotificationClosed wait for a signal.
+	// Defaults to 5 seconds.
+	SignalTimeout time.Duration
+
+	mu           sync.Mutex
+	lastID       uint32
+	open         map[uint32]bool
+	sent         []notify.Notification
+	history      []notify.HistoryEntry
+	signals      []notify.NotificationEvent
+	stats        notify.NotifierStats
+	capabilities []string
+	info         notify.ServerInformation
+	closed       bool
+	// changed is closed and replaced when a signal is emitted or the mock is closed
+	changed chan struct{}
+}
+
+var _ notify.Notifier = (*MockNotifier)(nil)
+
+// NewMockNotifier creates a MockNotifier with the same capabilities and server information as FakeDaemon.
+func NewMockNotifier() *MockNotifier {
+	return &MockNotifier{
+		open:         map[uint32]bool{},
+		capabilities: []string{"actions", "body", "body-markup", "icon-static", "sound"},
+		info: notify.ServerInformation{
+			Name:        "notifytest",
+			Vendor:      "notify",
+			Version:     "1.0.0",
+			SpecVersion: "1.2",
+		},
+		changed: make(chan struct{}),
+	}
+}
+
+// SetCapabilities sets the capabilities returned by GetCapabilities.
+func (m *MockNotifier) SetCapabilities(capabilities []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capabilities = append([]string{}, capabilities...)
+}
+
+// SetServerInformation sets the information returned by GetServerInformation.
+func (m *MockNotifier) SetServerInformation(info notify.ServerInformation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.info = info
+}
+
+// SentNotifications returns the notifications sent so far, in order.
+func (m *MockNotifier) SentNotifications() []notify.Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notify.Notification{}, m.sent...)
+}
+
+// Signals returns the signals emitted so far, in order.
+func (m *MockNotifier) Signals() []notify.NotificationEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notify.NotificationEvent{}, m.signals...)
+}
+
+// SimulateClose emits the NotificationClosed signal for id with reason, as if closed by the server.
+func (m *MockNotifier) SimulateClose(id uint32, reason notify.Reason) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emitClosed(id, reason)
+}
+
+// SimulateAction emits the ActionInvoked signal for id with the action key.
+func (m *MockNotifier) SimulateAction(id uint32, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.ActionSignalsReceived++
+	m.emit(notify.NotificationEvent{ID: id, Action: &notify.ActionInvokedSignal{ID: id, ActionKey: key}})
+}
+
+// emitClosed closes id and emits the NotificationClosed signal. Caller must hold m.mu.
+func (m *MockNotifier) emitClosed(id uint32, reason notify.Reason) {
+	delete(m.open, id)
+	now := time.Now()
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if e := &m.history[i]; e.ID == id && e.ClosedAt == nil {
+			e.ClosedAt, e.CloseReason = &now, &reason
+			break
+		}
+	}
+	m.stats.ClosedSignalsReceived++
+	m.emit(notify.NotificationEvent{ID: id, Closed: &notify.NotificationClosedSignal{ID: id, Reason: reason}})
+}
+
+// emit records e and wakes up waiting listeners. Caller must hold m.mu.
+func (m *MockNotifier) emit(e notify.NotificationEvent) {
+	m.signals = append(m.signals, e)
+	m.notifyChanged()
+}
+
+// notifyChanged wakes up waiting listeners. Caller must hold m.mu.
+func (m *MockNotifier) notifyChanged() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// waitForSignal waits for a signal emitted at or after index from, matching match.
+// Returns false if ctx is done first.
+func (m *MockNotifier) waitForSignal(ctx context.Context, from int, match func(notify.NotificationEvent) bool) (notify.NotificationEvent, bool) {
+	for {
+		m.mu.Lock()
+		for ; from < len(m.signals); from++ {
+			if e := m.signals[from]; match(e) {
+				m.mu.Unlock()
+				return e, true
+			}
+		}
+		changed := m.changed
+		m.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return notify.NotificationEvent{}, false
+		}
+	}
+}
+
+// SendNotification records n and returns its ID. n replaces the notification with n.ReplacesID if still open.
+func (m *MockNotifier) SendNotification(n notify.Notification) (uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, notify.ErrNotifierClosed
+	}
+	m.stats.SendCount++
+	m.sent = append(m.sent, n.Clone())
+
+	id := n.ReplacesID
+	if id == 0 || !m.open[id] {
+		m.lastID++
+		id = m.lastID
+	}
+	m.open[id] = true
+	m.history = append(m.history, notify.HistoryEntry{ID: id, Notification: n.Clone(), SentAt: time.Now()})
+	return id, nil
+}
+
+// SendNotificationSplit sends n with SendNotification, without splitting it.
+func (m *MockNotifier) SendNotificationSplit(n notify.Notification) ([]uint32, error) {
+	id, err := m.SendNotification(n)
+	if err != nil {
+		return nil, err
+	}
+	return []uint32{id}, nil
+}
+
+// GetCapabilities returns the capabilities set with SetCapabilities.
+func (m *MockNotifier) GetCapabilities() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.capabilities...), nil
+}
+
+// GetServerInformation returns the information set with SetServerInformation.
+func (m *MockNotifier) GetServerInformation() (notify.ServerInformation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.info, nil
+}
+
+// ServerCapabilities returns the capabilities and information of the mock.
+func (m *MockNotifier) ServerCapabilities() (notify.ServerCapabilities, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return notify.ServerCapabilities{
+		Capabilities:      append(notify.Capabilities{}, m.capabilities...),
+		ServerInformation: m.info,
+	}, nil
+}
+
+// Stats returns the counters of the mock.
+func (m *MockNotifier) Stats() notify.NotifierStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// CloseNotification closes the notification with id, emitting the NotificationClosed signal
+// with notify.ReasonClosedByCall. Returns a *notify.NotificationNotFoundError if id is not open.
+func (m *MockNotifier) CloseNotification(id uint32) error {
+	if id == 0 {
+		return notify.ErrInvalidNotificationID
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return notify.ErrNotifierClosed
+	}
+	m.stats.CloseCount++
+	if !m.open[id] {
+		m.stats.CloseErrorCount++
+		return &notify.NotificationNotFoundError{ID: id}
+	}
+	m.emitClosed(id, notify.ReasonClosedByCall)
+	return nil
+}
+
+// CloseNotificationSync closes the notification with id and returns its NotificationClosed signal.
+func (m *MockNotifier) CloseNotificationSync(ctx context.Context, id uint32) (notify.NotificationClosedSignal, error) {
+	if err := m.CloseNotification(id); err != nil {
+		return notify.NotificationClosedSignal{}, err
+	}
+	return notify.NotificationClosedSignal{ID: id, Reason: notify.ReasonClosedByCall}, nil
+}
+
+// SendAndWaitForClose sends n, and waits for its NotificationClosed signal.
+// If ctx is done first, the notification is closed with CloseNotification.
+func (m *MockNotifier) SendAndWaitForClose(ctx context.Context, n notify.Notification) (notify.NotificationClosedSignal, error) {
+	m.mu.Lock()
+	from := len(m.signals)
+	m.mu.Unlock()
+	id, err := m.SendNotification(n)
+	if err != nil {
+		return notify.NotificationClosedSignal{}, err
+	}
+	e, ok := m.waitForSignal(ctx, from, func(e notify.NotificationEvent) bool {
+		return e.ID == id && e.IsClosed()
+	})
+	if ok {
+		return *e.Closed, nil
+	}
+	return m.CloseNotificationSync(context.Background(), id)
+}
+
+// WaitForSignal waits for the next signal emitted for the notification with id.
+func (m *MockNotifier) WaitForSignal(ctx context.Context, id uint32) (notify.NotificationEvent, error) {
+	m.mu.Lock()
+	from := len(m.signals)
+	m.mu.Unlock()
+	e, ok := m.waitForSignal(ctx, from, func(e notify.NotificationEvent) bool { return e.ID == id })
+	if !ok {
+		return notify.NotificationEvent{}, ctx.Err()
+	}
+	return e, nil
+}
+
+// History returns all notifications sent, oldest first.
+func (m *MockNotifier) History() []notify.HistoryEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notify.HistoryEntry{}, m.history...)
+}
+
+// FlushCache does nothing, as the mock caches nothing.
+func (m *MockNotifier) FlushCache() {}
+
+// EventWriter does nothing, and returns a function doing nothing. Use Signals to inspect signals.
+func (m *MockNotifier) EventWriter(w io.Writer) func() {
+	return func() {}
+}
+
+// Shutdown closes the mock like Close.
+func (m *MockNotifier) Shutdown(ctx context.Context) error {
+	return m.Close()
+}
+
+// Close makes further calls to SendNotification and CloseNotification fail with notify.ErrNotifierClosed.
+func (m *MockNotifier) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		m.notifyChanged()
+	}
+	return nil
+}
+
+// IsOpen returns true until Close is called.
+func (m *MockNotifier) IsOpen() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.closed
+}
+
+// Clone returns m itself, as the options of notify cannot be applied to a mock,
+// so notifications sent through a clone are recorded by m, and closing a clone closes m.
+func (m *MockNotifier) Clone(opts ...notify.Option) (notify.Notifier, error) {
+	return m, nil
+}
+
+// Conn returns nil, as the mock has no connection.
+func (m *MockNotifier) Conn() *dbus.Conn {
+	return nil
+}
+
+// signalTimeout returns the timeout of the assertions.
+func (m *MockNotifier) signalTimeout() time.Duration {
+	if m.SignalTimeout > 0 {
+		return m.SignalTimeout
+	}
+	return defaultSignalTimeout
+}
@@ -0,0 +1,99 @@
+package notifytest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/esiqveland/notify"
+)
+
+// recordingTB records failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// runTB runs f with a recordingTB in a goroutine of its own, so Fatalf can stop it.
+func runTB(f func(tb *recordingTB)) []string {
+	tb := &recordingTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(tb)
+	}()
+	<-done
+	return tb.failures
+}
+
+func TestMockNotifier(t *testing.T) {
+	mock := NewMockNotifier()
+	var _ notify.Notifier = mock
+
+	id, err := mock.SendNotification(notify.Notification{Summary: "first"})
+	require.NoError(t, err)
+	replaced, err := mock.SendNotification(notify.Notification{Summary: "second", ReplacesID: id})
+	require.NoError(t, err)
+	require.Equal(t, id, replaced)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mock.SimulateAction(id, "open")
+	}()
+	e, err := mock.WaitForSignal(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "open", e.Action.ActionKey)
+
+	require.NoError(t, mock.CloseNotification(id))
+	require.True(t, notify.IsNotFound(mock.CloseNotification(id)))
+	require.Equal(t, notify.ReasonClosedByCall, *mock.History()[1].CloseReason)
+	require.EqualValues(t, 2, mock.Stats().SendCount)
+
+	require.NoError(t, mock.Close())
+	require.False(t, mock.IsOpen())
+	_, err = mock.SendNotification(notify.Notification{Summary: "closed"})
+	require.Equal(t, notify.ErrNotifierClosed, err)
+}
+
+func TestAssertions(t *testing.T) {
+	mock := NewMockNotifier()
+	mock.SignalTimeout = 50 * time.Millisecond
+	id, err := mock.SendNotification(notify.Notification{Summary: "hello"})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mock.SimulateAction(id, "open")
+		mock.SimulateClose(id, notify.ReasonDismissedByUser)
+	}()
+	require.Empty(t, runTB(func(tb *recordingTB) {
+		AssertActionInvoked(tb, mock, id, "open")
+		AssertNotificationClosed(tb, mock, id, notify.ReasonDismissedByUser)
+		require.Equal(t, "hello", RequireNotificationSent(tb, mock, "hello").Summary)
+	}))
+
+	failures := runTB(func(tb *recordingTB) {
+		require.False(t, AssertActionInvoked(tb, mock, id, "cancel"))
+		require.False(t, AssertNotificationClosed(tb, mock, id, notify.ReasonExpired))
+		RequireNotificationSent(tb, mock, "missing")
+		t.Error("RequireNotificationSent did not stop the test")
+	})
+	require.Len(t, failures, 3)
+	require.Contains(t, failures[0], `key "cancel"`)
+	require.Contains(t, failures[2], `"missing"`)
+}
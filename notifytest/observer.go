@@ -0,0 +1,246 @@
+package notifytest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/esiqveland/notify"
+)
+
+// Call is a call to the recording Notifier of an ObserverNotifier.
+type Call struct {
+	// Time the call was made
+	Time time.Time
+	// Method is the name of the Notifier method called, e.g. "SendNotification"
+	Method string
+	// Notification is the notification argument, for methods sending a notification
+	Notification *notify.Notification
+	// ID is the notification ID argument, or the ID returned by methods sending a notification
+	ID uint32
+	// Err is the error returned by the call, if any
+	Err error
+}
+
+// ObserverNotifier records the calls made to its recording Notifier, to inspect or replay them.
+// It is safe for concurrent use.
+type ObserverNotifier struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewObserverNotifier creates an ObserverNotifier and its recording Notifier, which passes all calls
+// on to base after recording them. If base is nil, a new MockNotifier is used.
+//
+// The methods sending, closing and waiting for notifications, querying the server, and closing the Notifier
+// are recorded. Accessors such as Stats, History and Conn are not.
+func NewObserverNotifier(base notify.Notifier) (*ObserverNotifier, notify.Notifier) {
+	if base == nil {
+		base = NewMockNotifier()
+	}
+	o := &ObserverNotifier{}
+	return o, &recordingNotifier{Notifier: base, o: o}
+}
+
+// Calls returns the calls recorded so far, in order.
+func (o *ObserverNotifier) Calls() []Call {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Call{}, o.calls...)
+}
+
+func (o *ObserverNotifier) record(c Call) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, c)
+}
+
+// PlaybackTo sends the notifications of all recorded SendNotification calls to target, in order.
+// ReplacesID of a notification replacing one sent earlier in the recording is translated
+// to the ID target returned for it, so replacements replay as recorded.
+// Stops at the first error sending a notification.
+func (o *ObserverNotifier) PlaybackTo(target notify.Notifier) error {
+	ids := map[uint32]uint32{}
+	for _, c := range o.Calls() {
+		if c.Method != "SendNotification" || c.Notification == nil {
+			continue
+		}
+		n := c.Notification.Clone()
+		if id, ok := ids[n.ReplacesID]; ok {
+			n.ReplacesID = id
+		}
+		id, err := target.SendNotification(n)
+		if err != nil {
+			return err
+		}
+		if c.ID != 0 {
+			ids[c.ID] = id
+		}
+	}
+	return nil
+}
+
+// callRecord is the JSON form of a Call written by DumpJSON.
+type callRecord struct {
+	Time         time.Time           `json:"time"`
+	Method       string              `json:"method"`
+	ID           uint32              `json:"id,omitempty"`
+	Notification *notificationRecord `json:"notification,omitempty"`
+	Err          string              `json:"error,omitempty"`
+}
+
+// notificationRecord is the JSON form of a notify.Notification, with hints as their values.
+type notificationRecord struct {
+	AppName         string                 `json:"app_name,omitempty"`
+	ReplacesID      uint32                 `json:"replaces_id,omitempty"`
+	AppIcon         string                 `json:"app_icon,omitempty"`
+	Summary         string                 `json:"summary"`
+	Body            string                 `json:"body,omitempty"`
+	Actions         []notify.Action        `json:"actions,omitempty"`
+	Hints           map[string]interface{} `json:"hints,omitempty"`
+	Urgency         *notify.Urgency        `json:"urgency,omitempty"`
+	ExpireTimeoutMs int64                  `json:"expire_timeout_ms"`
+}
+
+// DumpJSON writes all recorded calls to w as a JSON array, for example:
+//
+//	[{"time":"2024-01-02T15:04:05Z","method":"SendNotification","id":1,"notification":{"summary":"Hello","expire_timeout_ms":0}}]
+func (o *ObserverNotifier) DumpJSON(w io.Writer) error {
+	calls := o.Calls()
+	records := make([]callRecord, 0, len(calls))
+	for _, c := range calls {
+		record := callRecord{Time: c.Time, Method: c.Method, ID: c.ID}
+		if c.Err != nil {
+			record.Err = c.Err.Error()
+		}
+		if n := c.Notification; n != nil {
+			record.Notification = &notificationRecord{
+				AppName:         n.AppName,
+				ReplacesID:      n.ReplacesID,
+				AppIcon:         n.AppIcon,
+				Summary:         n.Summary,
+				Body:            n.Body,
+				Actions:         n.Actions,
+				Urgency:         n.Urgency,
+				ExpireTimeoutMs: n.ExpireTimeout.Milliseconds(),
+			}
+			if len(n.Hints) > 0 {
+				record.Notification.Hints = make(map[string]interface{}, len(n.Hints))
+				for k, v := range n.Hints {
+					record.Notification.Hints[k] = v.Value()
+				}
+			}
+		}
+		records = append(records, record)
+	}
+	return json.NewEncoder(w).Encode(records)
+}
+
+// recordingNotifier records calls in o before passing them on to the embedded Notifier.
+type recordingNotifier struct {
+	notify.Notifier
+	o *ObserverNotifier
+}
+
+// recordSend records a call sending n, which returned id and err.
+func (r *recordingNotifier) recordSend(method string, start time.Time, n notify.Notification, id uint32, err error) {
+	n = n.Clone()
+	r.o.record(Call{Time: start, Method: method, Notification: &n, ID: id, Err: err})
+}
+
+// Unwrap returns the Notifier r passes calls on to.
+func (r *recordingNotifier) Unwrap() notify.Notifier {
+	return r.Notifier
+}
+
+func (r *recordingNotifier) SendNotification(n notify.Notification) (uint32, error) {
+	start := time.Now()
+	id, err := r.Notifier.SendNotification(n)
+	r.recordSend("SendNotification", start, n, id, err)
+	return id, err
+}
+
+func (r *recordingNotifier) SendNotificationSplit(n notify.Notification) ([]uint32, error) {
+	start := time.Now()
+	ids, err := r.Notifier.SendNotificationSplit(n)
+	var id uint32
+	if len(ids) > 0 {
+		id = ids[0]
+	}
+	r.recordSend("SendNotificationSplit", start, n, id, err)
+	return ids, err
+}
+
+func (r *recordingNotifier) SendAndWaitForClose(ctx context.Context, n notify.Notification) (notify.NotificationClosedSignal, error) {
+	start := time.Now()
+	s, err := r.Notifier.SendAndWaitForClose(ctx, n)
+	r.recordSend("SendAndWaitForClose", start, n, s.ID, err)
+	return s, err
+}
+
+func (r *recordingNotifier) CloseNotification(id uint32) error {
+	start := time.Now()
+	err := r.Notifier.CloseNotification(id)
+	r.o.record(Call{Time: start, Method: "CloseNotification", ID: id, Err: err})
+	return err
+}
+
+func (r *recordingNotifier) CloseNotificationSync(ctx context.Context, id uint32) (notify.NotificationClosedSignal, error) {
+	start := time.Now()
+	s, err := r.Notifier.CloseNotificationSync(ctx, id)
+	r.o.record(Call{Time: start, Method: "CloseNotificationSync", ID: id, Err: err})
+	return s, err
+}
+
+func (r *recordingNotifier) WaitForSignal(ctx context.Context, id uint32) (notify.NotificationEvent, error) {
+	start := time.Now()
+	e, err := r.Notifier.WaitForSignal(ctx, id)
+	r.o.record(Call{Time: start, Method: "WaitForSignal", ID: id, Err: err})
+	return e, err
+}
+
+func (r *recordingNotifier) GetCapabilities() ([]string, error) {
+	start := time.Now()
+	caps, err := r.Notifier.GetCapabilities()
+	r.o.record(Call{Time: start, Method: "GetCapabilities", Err: err})
+	return caps, err
+}
+
+func (r *recordingNotifier) GetServerInformation() (notify.ServerInformation, error) {
+	start := time.Now()
+	info, err := r.Notifier.GetServerInformation()
+	r.o.record(Call{Time: start, Method: "GetServerInformation", Err: err})
+	return info, err
+}
+
+func (r *recordingNotifier) ServerCapabilities() (notify.ServerCapabilities, error) {
+	start := time.Now()
+	caps, err := r.Notifier.ServerCapabilities()
+	r.o.record(Call{Time: start, Method: "ServerCapabilities", Err: err})
+	return caps, err
+}
+
+func (r *recordingNotifier) Shutdown(ctx context.Context) error {
+	start := time.Now()
+	err := r.Notifier.Shutdown(ctx)
+	r.o.record(Call{Time: start, Method: "Shutdown", Err: err})
+	return err
+}
+
+func (r *recordingNotifier) Close() error {
+	start := time.Now()
+	err := r.Notifier.Close()
+	r.o.record(Call{Time: start, Method: "Close", Err: err})
+	return err
+}
+
+// Clone clones the embedded Notifier, and records the calls to the clone in the same ObserverNotifier.
+func (r *recordingNotifier) Clone(opts ...notify.Option) (notify.Notifier, error) {
+	clone, err := r.Notifier.Clone(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingNotifier{Notifier: clone, o: r.o}, nil
+}
@@ -0,0 +1,49 @@
+package notifytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/esiqveland/notify"
+)
+
+func TestObserverNotifier(t *testing.T) {
+	observer, recording := NewObserverNotifier(nil)
+
+	first, err := recording.SendNotification(notify.Notification{Summary: "first"})
+	require.NoError(t, err)
+	_, err = recording.SendNotification(notify.Notification{Summary: "second"})
+	require.NoError(t, err)
+	_, err = recording.SendNotification(notify.Notification{Summary: "first updated", ReplacesID: first})
+	require.NoError(t, err)
+	require.NoError(t, recording.CloseNotification(first))
+
+	calls := observer.Calls()
+	require.Len(t, calls, 4)
+	require.Equal(t, "SendNotification", calls[0].Method)
+	require.Equal(t, first, calls[0].ID)
+	require.Equal(t, "CloseNotification", calls[3].Method)
+	require.Nil(t, calls[3].Notification)
+
+	// the target hands out other IDs, and replacements follow them
+	target := NewMockNotifier()
+	_, err = target.SendNotification(notify.Notification{Summary: "already shown"})
+	require.NoError(t, err)
+	require.NoError(t, observer.PlaybackTo(target))
+	sent := target.SentNotifications()
+	require.Len(t, sent, 4)
+	require.Equal(t, []string{"already shown", "first", "second", "first updated"},
+		[]string{sent[0].Summary, sent[1].Summary, sent[2].Summary, sent[3].Summary})
+	require.Equal(t, uint32(2), sent[3].ReplacesID)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, observer.DumpJSON(buf))
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &records))
+	require.Len(t, records, 4)
+	require.Equal(t, "SendNotification", records[0]["method"])
+	require.Equal(t, "first", records[0]["notification"].(map[string]interface{})["summary"])
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// NotifierOptions configures a Notifier created with NewWithOptions.
+// It is an alternative to the options of New, for configuration that is loaded or compared as a value.
+// Zero fields keep the defaults of New.
+type NotifierOptions struct {
+	// Logger is used for warnings and errors, see WithLogger
+	Logger logger
+	// OnAction is called for ActionInvoked signals, see WithOnAction
+	OnAction ActionInvokedHandler
+	// OnClosed is called for NotificationClosed signals, see WithOnClosed
+	OnClosed NotificationClosedHandler
+	// SignalChannelSize bounds the number of buffered signals, dropping signals beyond it,
+	// see WithSignalBufferSize. 0 keeps the default of not dropping signals.
+	SignalChannelSize int
+	// CallTimeout bounds each dbus call, see WithCallTimeout. 0 means no timeout.
+	CallTimeout time.Duration
+}
+
+// DefaultNotifierOptions returns the options New uses without any options,
+// with the default logger logging to the standard logger.
+func DefaultNotifierOptions() NotifierOptions {
+	return NotifierOptions{
+		Logger:   &loggerWrapper{"notify: "},
+		OnAction: func(s *ActionInvokedSignal) {},
+		OnClosed: func(s *NotificationClosedSignal) {},
+	}
+}
+
+// NewWithOptions creates a new Notifier using conn, configured by opts.
+// It is equivalent to New with the options matching the set fields of opts.
+func NewWithOptions(conn *dbus.Conn, opts NotifierOptions) (Notifier, error) {
+	return New(conn, opts.options()...)
+}
+
+// options converts o to the options of New.
+func (o NotifierOptions) options() []option {
+	var opts []option
+	if o.Logger != nil {
+		opts = append(opts, WithLogger(o.Logger))
+	}
+	if o.OnAction != nil {
+		opts = append(opts, WithOnAction(o.OnAction))
+	}
+	if o.OnClosed != nil {
+		opts = append(opts, WithOnClosed(o.OnClosed))
+	}
+	if o.SignalChannelSize != 0 {
+		opts = append(opts, WithSignalBufferSize(o.SignalChannelSize))
+	}
+	if o.CallTimeout != 0 {
+		opts = append(opts, WithCallTimeout(o.CallTimeout))
+	}
+	return opts
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifierOptions(t *testing.T) {
+	defaults := newNotifier(DefaultNotifierOptions().options()...)
+	require.Equal(t, channelBufferSize, defaults.signalBufferSize)
+	require.False(t, defaults.dropSignals)
+	require.Zero(t, defaults.callTimeout)
+
+	var closed *NotificationClosedSignal
+	n := newNotifier(NotifierOptions{
+		Logger:            discardLogger{},
+		OnClosed:          func(s *NotificationClosedSignal) { closed = s },
+		SignalChannelSize: 3,
+		CallTimeout:       time.Second,
+	}.options()...)
+	require.Equal(t, discardLogger{}, n.log)
+	require.Equal(t, 3, n.signalBufferSize)
+	require.True(t, n.dropSignals)
+	require.Equal(t, time.Second, n.callTimeout)
+	n.onClosed(&NotificationClosedSignal{ID: 1})
+	require.Equal(t, uint32(1), closed.ID)
+}
+
+func TestCallContext(t *testing.T) {
+	ctx, cancel := newNotifier().callContext()
+	defer cancel()
+	_, ok := ctx.Deadline()
+	require.False(t, ok)
+
+	ctx, cancel = newNotifier(WithCallTimeout(time.Minute)).callContext()
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
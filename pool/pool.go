@@ -0,0 +1,117 @@
+// Package pool provides a pool of private, authenticated session bus connections
+// for sending a high volume of notifications concurrently.
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/esiqveland/notify"
+)
+
+// ErrPoolClosed is returned when using a ConnectionPool after Close has been called.
+var ErrPoolClosed = errors.New("notify/pool: pool is closed")
+
+// ConnectionPool holds a fixed number of private session bus connections.
+// It is safe for concurrent use. Get blocks while all connections are borrowed.
+type ConnectionPool struct {
+	conns     chan *dbus.Conn
+	all       []*dbus.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// NewConnectionPool opens size private connections to the session bus and authenticates them.
+// Caller is responsible for calling Close() to release the connections.
+func NewConnectionPool(size int) (*ConnectionPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("notify/pool: invalid pool size: %d", size)
+	}
+	p := &ConnectionPool{
+		conns: make(chan *dbus.Conn, size),
+		all:   make([]*dbus.Conn, 0, size),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		conn, err := openConn()
+		if err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+		p.all = append(p.all, conn)
+		p.conns <- conn
+	}
+	return p, nil
+}
+
+func openConn() (*dbus.Conn, error) {
+	return notify.GetDefaultSession()
+}
+
+// Get borrows a connection from the pool, blocking until one is available.
+// The returned func must be called to give the connection back to the pool.
+// If the pool is closed, Get returns a nil connection.
+func (p *ConnectionPool) Get() (*dbus.Conn, func()) {
+	// check done first, as select picks at random when a connection is also available
+	select {
+	case <-p.done:
+		return nil, func() {}
+	default:
+	}
+
+	select {
+	case conn := <-p.conns:
+		select {
+		case <-p.done:
+			// closed while waiting
+			return nil, func() {}
+		default:
+		}
+		var once sync.Once
+		release := func() {
+			once.Do(func() {
+				p.conns <- conn
+			})
+		}
+		return conn, release
+	case <-p.done:
+		return nil, func() {}
+	}
+}
+
+// SendNotification borrows a connection, sends n on it and gives the connection back.
+func (p *ConnectionPool) SendNotification(n notify.Notification) (uint32, error) {
+	conn, release := p.Get()
+	defer release()
+	if conn == nil {
+		return 0, ErrPoolClosed
+	}
+	return notify.SendNotification(conn, n)
+}
+
+// Close closes all connections in the pool, including borrowed ones.
+// It is safe to be called multiple times.
+func (p *ConnectionPool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		// drain idle connections, so they are never handed out again
+	drain:
+		for {
+			select {
+			case <-p.conns:
+			default:
+				break drain
+			}
+		}
+		for _, conn := range p.all {
+			if err := conn.Close(); err != nil && p.err == nil {
+				p.err = err
+			}
+		}
+	})
+	return p.err
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esiqveland/notify"
+	"github.com/esiqveland/notify/notifytest"
+)
+
+func TestNewConnectionPoolInvalidSize(t *testing.T) {
+	_, err := NewConnectionPool(0)
+	require.Error(t, err)
+}
+
+// startFakeDaemon starts a fake notification server, and points the session bus at it
+// until the returned func is called.
+func startFakeDaemon(t *testing.T) (*notifytest.FakeDaemon, func()) {
+	daemon, err := notifytest.NewFakeDaemon()
+	if errors.Is(err, notifytest.ErrNoDBusDaemon) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	old, ok := os.LookupEnv("DBUS_SESSION_BUS_ADDRESS")
+	_ = os.Setenv("DBUS_SESSION_BUS_ADDRESS", daemon.Address())
+	return daemon, func() {
+		if ok {
+			_ = os.Setenv("DBUS_SESSION_BUS_ADDRESS", old)
+		} else {
+			_ = os.Unsetenv("DBUS_SESSION_BUS_ADDRESS")
+		}
+		daemon.Close()
+	}
+}
+
+func TestConnectionPoolGetRelease(t *testing.T) {
+	daemon, stop := startFakeDaemon(t)
+	defer stop()
+
+	p, err := NewConnectionPool(2)
+	require.NoError(t, err)
+	defer p.Close()
+
+	first, releaseFirst := p.Get()
+	require.NotNil(t, first)
+	second, releaseSecond := p.Get()
+	require.NotNil(t, second)
+	require.True(t, first != second)
+
+	// all connections are borrowed, so Get blocks until one is released
+	got := make(chan *dbus.Conn)
+	go func() {
+		conn, release := p.Get()
+		release()
+		got <- conn
+	}()
+	select {
+	case <-got:
+		t.Fatal("Get returned while all connections were borrowed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	releaseFirst()
+	// releasing twice is a no-op
+	releaseFirst()
+	select {
+	case conn := <-got:
+		require.True(t, conn == first)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get did not return after release")
+	}
+	releaseSecond()
+
+	_, err = p.SendNotification(notify.Notification{Summary: "pooled"})
+	require.NoError(t, err)
+	require.Len(t, daemon.SentNotifications(), 1)
+}
+
+func TestConnectionPoolClosed(t *testing.T) {
+	_, stop := startFakeDaemon(t)
+	defer stop()
+
+	p, err := NewConnectionPool(2)
+	require.NoError(t, err)
+	borrowed, release := p.Get()
+	require.NotNil(t, borrowed)
+
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close())
+	// giving back a connection after Close must not block
+	release()
+
+	for i := 0; i < 10; i++ {
+		conn, release := p.Get()
+		require.Nil(t, conn)
+		release()
+		_, err = p.SendNotification(notify.Notification{Summary: "closed"})
+		require.Equal(t, ErrPoolClosed, err)
+	}
+}
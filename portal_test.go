@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXDGPortalPriority(t *testing.T) {
+	h, err := XDGPortalPriority(XDGPortalPriorityHigh)
+	require.NoError(t, err)
+	require.Equal(t, "priority", h.ID)
+	require.Equal(t, "high", h.Variant.Value())
+
+	_, err = XDGPortalPriority("critical")
+	require.Error(t, err)
+}
+
+func TestPortalNotification(t *testing.T) {
+	n := Notification{
+		AppName: "ignored",
+		AppIcon: "mail-unread",
+		Summary: "summary",
+		Body:    "body",
+		Urgency: UrgencyCritical.Ptr(),
+		Actions: []Action{NewDefaultAction("Open"), {Key: "reply", Label: "Reply"}},
+	}
+	n.AddHint(HintSoundWithName("bell"))
+
+	props := portalNotification(n)
+	require.Equal(t, "summary", props["title"].Value())
+	require.Equal(t, "body", props["body"].Value())
+	require.Equal(t, "urgent", props["priority"].Value())
+	require.Equal(t, ActionKeyDefault, props["default-action"].Value())
+	require.Equal(t, []map[string]dbus.Variant{
+		{"label": dbus.MakeVariant("Reply"), "action": dbus.MakeVariant("reply")},
+	}, props["buttons"].Value())
+	require.Equal(t, "(sv)", props["icon"].Signature().String())
+	require.NotContains(t, props, "sound-name")
+
+	// portal hints take precedence
+	high, err := XDGPortalPriority(XDGPortalPriorityHigh)
+	require.NoError(t, err)
+	n.AddHint(high)
+	n.AddHint(XDGPortalDefaultAction("view"))
+	n.AddHint(XDGPortalButtons([]XDGPortalButton{{Label: "Dismiss", Action: "dismiss"}}))
+	props = portalNotification(n)
+	require.Equal(t, "high", props["priority"].Value())
+	require.Equal(t, "view", props["default-action"].Value())
+	require.Equal(t, []map[string]dbus.Variant{
+		{"label": dbus.MakeVariant("Dismiss"), "action": dbus.MakeVariant("dismiss")},
+	}, props["buttons"].Value())
+}
+
+func TestSignalNotificationID(t *testing.T) {
+	require.EqualValues(t, 7, signalNotificationID(uint32(7)))
+	require.EqualValues(t, 7, signalNotificationID("7"))
+	require.EqualValues(t, 0, signalNotificationID("other-app-id"))
+	require.EqualValues(t, 0, signalNotificationID(int64(7)))
+}
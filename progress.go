@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// HintProgress sets the "value" hint, which servers that support it render as a progress bar.
+// percent should be in the range 0-100.
+func HintProgress(percent int) Hint {
+	return Hint{
+		ID:      "value",
+		Variant: dbus.MakeVariant(int32(percent)),
+	}
+}
+
+// dunstProgressLabelHintID is the ID of the hint labelling the progress bar in Dunst.
+const dunstProgressLabelHintID = "x-dunst-progress-bar-label"
+
+// HintProgressWithText returns the "value" hint of HintProgress, and a hint labelling the progress bar
+// with text, e.g. "3 of 7 files". The label hint is specific to Dunst, other servers ignore it.
+func HintProgressWithText(percent int, text string) []Hint {
+	return []Hint{
+		HintProgress(percent),
+		{
+			ID:      dunstProgressLabelHintID,
+			Variant: dbus.MakeVariant(text),
+		},
+	}
+}
+
+// AddProgressHints adds the hints of HintProgressWithText to n, or only the hint of HintProgress
+// if label is empty. Returns an error if percent is not in the range 0-100.
+func AddProgressHints(n *Notification, percent int, label string) error {
+	if err := validatePercent(percent); err != nil {
+		return err
+	}
+	if label == "" {
+		n.AddHint(HintProgress(percent))
+		return nil
+	}
+	for _, hint := range HintProgressWithText(percent, label) {
+		n.AddHint(hint)
+	}
+	return nil
+}
+
+func validatePercent(percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("invalid progress: %d is not in the range 0-100", percent)
+	}
+	return nil
+}
+
+// NewProgressNotification creates a low urgency notification showing percent as progress,
+// both with the "value" hint and as a body of "<percent>%" for servers without progress bars.
+// Returns an error if percent is not in the range 0-100.
+func NewProgressNotification(appName, summary string, percent int) (Notification, error) {
+	if err := validatePercent(percent); err != nil {
+		return Notification{}, err
+	}
+	n := Notification{
+		AppName: appName,
+		Summary: summary,
+		Body:    fmt.Sprintf("%d%%", percent),
+		Urgency: UrgencyLow.Ptr(),
+	}
+	n.AddHint(HintProgress(percent))
+	return n, nil
+}
+
+// UpdateProgress replaces the notification with id by a clone of original with progress set to percent,
+// and returns the ID to pass to the next call:
+//
+//	id, err = notify.UpdateProgress(ctx, notifier, id, original, 50)
+//
+// original is not modified. No update is sent if ctx is done.
+func UpdateProgress(ctx context.Context, notifier Notifier, id uint32, original Notification, percent int) (uint32, error) {
+	if err := validatePercent(percent); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	updated := original.WithReplace(id)
+	updated.Body = fmt.Sprintf("%d%%", percent)
+	updated.AddHint(HintProgress(percent))
+	return notifier.SendNotification(updated)
+}
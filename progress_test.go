@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProgressNotification(t *testing.T) {
+	n, err := NewProgressNotification("app", "Downloading", 42)
+	require.NoError(t, err)
+	require.Equal(t, "app", n.AppName)
+	require.Equal(t, "Downloading", n.Summary)
+	require.Equal(t, "42%", n.Body)
+	require.Equal(t, int32(42), n.Hints["value"].Value())
+	urgency, ok := GetUrgency(n)
+	require.True(t, ok)
+	require.Equal(t, UrgencyLow, urgency)
+
+	_, err = NewProgressNotification("app", "Downloading", 101)
+	require.Error(t, err)
+	_, err = NewProgressNotification("app", "Downloading", -1)
+	require.Error(t, err)
+}
+
+func TestAddProgressHints(t *testing.T) {
+	n := Notification{Summary: "Copying"}
+	require.NoError(t, AddProgressHints(&n, 30, "3 of 10 files"))
+	require.Equal(t, int32(30), n.Hints["value"].Value())
+	require.Equal(t, "3 of 10 files", n.Hints["x-dunst-progress-bar-label"].Value())
+
+	n = Notification{Summary: "Copying"}
+	require.NoError(t, AddProgressHints(&n, 50, ""))
+	require.Equal(t, int32(50), n.Hints["value"].Value())
+	require.NotContains(t, n.Hints, "x-dunst-progress-bar-label")
+
+	require.Error(t, AddProgressHints(&n, 101, "too much"))
+	require.Equal(t, int32(50), n.Hints["value"].Value())
+}
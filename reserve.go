@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// IDReserver is implemented by the Notifier returned by New, for sending notifications
+// without missing any of their signals. See ReserveID.
+type IDReserver interface {
+	// ReserveID reserves an ID to listen for signals with WaitForSignal, before sending the notification with SendWithID.
+	ReserveID() uint32
+	// SendWithID sends n as a new notification, and delivers its signals to the listeners of reserved.
+	SendWithID(reserved uint32, n Notification) (uint32, error)
+}
+
+var _ IDReserver = (*notifier)(nil)
+
+// ReserveID reserves an ID for a notification not sent yet, to be sent with SendWithID.
+//
+// Listening for the signals of a notification with WaitForSignal after SendNotification returns
+// misses signals received before listening started, e.g. when the user clicks an action right away.
+// Listening with the reserved ID instead, before sending, is race-free:
+// every signal of the notification sent with SendWithID is delivered to the listeners of the reserved ID.
+//
+// Notification servers hand out the IDs of notifications themselves, so reserved IDs are only known to n.
+// They count down from the top of the uint32 range, and do not collide with the IDs of servers in practice.
+// An ID stays reserved until it is sent with SendWithID, or n is closed.
+func (n *notifier) ReserveID() uint32 {
+	id := math.MaxUint32 - atomic.AddUint32(&n.lastReserved, 1) + 1
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	n.reserved[id] = true
+	return id
+}
+
+// SendWithID sends note as a new notification for the ID reserved with ReserveID, and returns the ID given by the server.
+// Use the returned ID for everything else, e.g. closing the notification.
+//
+// All signals of the notification, including those received before SendWithID returns, are delivered to the listeners
+// of the reserved ID, until the notification is closed. Listeners of the returned ID receive them as well.
+// Signals received by listeners of the reserved ID carry the returned ID.
+//
+// The reserved ID is not sent to the server. Note is not split, even with WithBodySplitting.
+// Returns ErrIDNotReserved if reserved was not reserved, or was already sent.
+// If sending fails, the ID stays reserved.
+func (n *notifier) SendWithID(reserved uint32, note Notification) (uint32, error) {
+	n.waitersMu.Lock()
+	if !n.reserved[reserved] {
+		n.waitersMu.Unlock()
+		return 0, ErrIDNotReserved
+	}
+	n.sendsWithID++
+	n.waitersMu.Unlock()
+
+	ctx, cancel := n.callContext()
+	defer cancel()
+	id, err := n.sendNotification(ctx, note)
+
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	// signals for id may have been received before the server replied
+	received := n.sentEvents
+	n.sendsWithID--
+	if n.sendsWithID == 0 {
+		n.sentEvents = nil
+	}
+	if err != nil {
+		return id, err
+	}
+	delete(n.reserved, reserved)
+	n.reservedFor[id] = reserved
+	for _, e := range received {
+		if e.ID == id {
+			n.deliverReserved(e)
+		}
+	}
+	return id, nil
+}
+
+// deliverReserved hands e to all event listeners of the ID reserved for its notification, if any, and unregisters them.
+// Caller must hold n.waitersMu.
+func (n *notifier) deliverReserved(e NotificationEvent) {
+	reserved, ok := n.reservedFor[e.ID]
+	if !ok {
+		return
+	}
+	n.deliverEventTo(reserved, e)
+	n.untrackWaiter(reserved)
+	if e.IsClosed() {
+		delete(n.reservedFor, e.ID)
+	}
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusInterface        = "org.freedesktop.DBus"
+	dbusNameOwnerChanged = "org.freedesktop.DBus.NameOwnerChanged"
+)
+
+// WithOnDaemonRestart sets a handler called when the notification server is restarted, e.g. after a crash.
+// Before h is called, cached capabilities and server information are flushed, see FlushCache.
+//
+// A restart is detected when the bus name of the server gets a new owner. This also happens when
+// a server is started after the Notifier, or replaces the running server.
+// Notifications shown by the previous server are gone, and signals for them will not arrive.
+func WithOnDaemonRestart(h func()) option {
+	return func(n *notifier) {
+		n.onDaemonRestart = h
+	}
+}
+
+// nameOwnerMatch is the match rule for owner changes of the bus name of the server.
+func (n *notifier) nameOwnerMatch() []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchSender(dbusInterface),
+		dbus.WithMatchInterface(dbusInterface),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, n.endpoint.dest),
+	}
+}
+
+// handleNameOwnerChanged calls the restart handler when the bus name of the server gets a new owner.
+// The connection may be shared, so owner changes of other names are ignored.
+func (n *notifier) handleNameOwnerChanged(signal *dbus.Signal) {
+	if n.onDaemonRestart == nil || len(signal.Body) != 3 {
+		return
+	}
+	name, _ := signal.Body[0].(string)
+	newOwner, _ := signal.Body[2].(string)
+	if name != n.endpoint.dest || newOwner == "" {
+		return
+	}
+	n.log.Printf("Notification server %v has a new owner: %v", name, newOwner)
+	n.FlushCache()
+	n.runHandler(dbusNameOwnerChanged, n.onDaemonRestart)
+}
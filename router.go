@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+)
+
+// Route sends notifications matching Match to Target, see NotifierRouter.
+type Route struct {
+	Match  func(Notification) bool
+	Target Notifier
+}
+
+// NotifierRouter is a Notifier that sends each notification to the Notifier of the first matching route,
+// e.g. to send critical notifications to a different notification server than others.
+// Notifications matching no route are sent to the fallback Notifier.
+//
+// Only SendNotification and SendNotificationSplit are routed, the other methods go straight to the fallback.
+// As the IDs of notifications sent to a target are only known to that target,
+// close and wait for them through the target.
+type NotifierRouter struct {
+	Notifier
+
+	routes []Route
+}
+
+// NewNotifierRouter creates a NotifierRouter with routes, evaluated in order, and fallback.
+func NewNotifierRouter(routes []Route, fallback Notifier) *NotifierRouter {
+	return &NotifierRouter{
+		Notifier: fallback,
+		routes:   append([]Route(nil), routes...),
+	}
+}
+
+// Unwrap returns the fallback Notifier of r.
+func (r *NotifierRouter) Unwrap() Notifier {
+	return r.Notifier
+}
+
+// Target returns the Notifier n is sent to.
+func (r *NotifierRouter) Target(n Notification) Notifier {
+	for _, route := range r.routes {
+		if route.Match(n) {
+			return route.Target
+		}
+	}
+	return r.Notifier
+}
+
+// SendNotification sends n with the Notifier of the first route matching n, or the fallback.
+func (r *NotifierRouter) SendNotification(n Notification) (uint32, error) {
+	return r.Target(n).SendNotification(n)
+}
+
+// SendNotificationSplit sends n with the Notifier of the first route matching n, or the fallback.
+func (r *NotifierRouter) SendNotificationSplit(n Notification) ([]uint32, error) {
+	return r.Target(n).SendNotificationSplit(n)
+}
+
+// Close does nothing: the targets and the fallback are left open, and must be closed by the caller.
+func (r *NotifierRouter) Close() error {
+	return nil
+}
+
+// Shutdown does nothing, like Close.
+func (r *NotifierRouter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// MatchUrgency matches notifications with urgency u, see GetUrgency.
+// Notifications without an urgency match UrgencyNormal.
+func MatchUrgency(u Urgency) func(Notification) bool {
+	return func(n Notification) bool {
+		urgency, _ := GetUrgency(n)
+		return urgency == u
+	}
+}
+
+// MatchAppName matches notifications with AppName name.
+func MatchAppName(name string) func(Notification) bool {
+	return func(n Notification) bool {
+		return n.AppName == name
+	}
+}
+
+// MatchCategory matches notifications with the category hint set to c.
+func MatchCategory(c Category) func(Notification) bool {
+	return func(n Notification) bool {
+		category, ok := n.Hints["category"].Value().(string)
+		return ok && Category(category) == c
+	}
+}
@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchers(t *testing.T) {
+	n := Notification{AppName: "mail"}
+	require.True(t, MatchUrgency(UrgencyNormal)(n))
+	require.True(t, MatchAppName("mail")(n))
+	require.False(t, MatchAppName("chat")(n))
+	require.False(t, MatchCategory(CategoryEmailArrived)(n))
+
+	n.Urgency = UrgencyCritical.Ptr()
+	n.AddHint(HintFromCategory(CategoryEmailArrived))
+	require.True(t, MatchUrgency(UrgencyCritical)(n))
+	require.False(t, MatchUrgency(UrgencyNormal)(n))
+	require.True(t, MatchCategory(CategoryEmailArrived)(n))
+	require.False(t, MatchCategory(CategoryIM)(n))
+}
+
+func TestNotifierRouterTarget(t *testing.T) {
+	critical, fallback := newNotifier(), newNotifier()
+	router := NewNotifierRouter([]Route{{Match: MatchUrgency(UrgencyCritical), Target: critical}}, fallback)
+
+	require.True(t, router.Target(Notification{Urgency: UrgencyCritical.Ptr()}) == Notifier(critical))
+	require.True(t, router.Target(Notification{Urgency: UrgencyLow.Ptr()}) == Notifier(fallback))
+}
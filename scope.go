@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrScopeClosed is returned when sending through a NotificationScope that has been closed.
+var ErrScopeClosed = errors.New("notify: notification scope is closed")
+
+// NotificationScope is a Notifier that tracks the notifications sent through it,
+// and closes them all when the scope is closed.
+// It wraps a base Notifier, which is left open, so several scopes can share the same base.
+type NotificationScope struct {
+	Notifier
+
+	mu     sync.Mutex
+	ids    map[uint32]struct{}
+	closed bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// NewNotificationScope creates a scope sending through base.
+// When ctx is done or the returned CancelFunc is called, all notifications sent
+// through the scope are closed. The CancelFunc waits for this to finish.
+func NewNotificationScope(ctx context.Context, base Notifier) (*NotificationScope, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &NotificationScope{
+		Notifier: base,
+		ids:      map[uint32]struct{}{},
+		done:     make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-s.done:
+		}
+	}()
+	return s, func() {
+		cancel()
+		<-s.done
+	}
+}
+
+// Unwrap returns the base Notifier of s.
+func (s *NotificationScope) Unwrap() Notifier {
+	return s.Notifier
+}
+
+// SendNotification sends n through the base Notifier and tracks its ID.
+// If the base Notifier splits n, all parts are tracked, and the ID of the first is returned.
+func (s *NotificationScope) SendNotification(n Notification) (uint32, error) {
+	ids, err := s.SendNotificationSplit(n)
+	if len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], err
+}
+
+// SendNotificationSplit sends n through the base Notifier and tracks the IDs of all parts sent.
+func (s *NotificationScope) SendNotificationSplit(n Notification) ([]uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrScopeClosed
+	}
+	ids, err := s.Notifier.SendNotificationSplit(n)
+	for _, id := range ids {
+		s.ids[id] = struct{}{}
+	}
+	return ids, err
+}
+
+// CloseNotification closes the notification with id and stops tracking it.
+func (s *NotificationScope) CloseNotification(id uint32) error {
+	s.mu.Lock()
+	delete(s.ids, id)
+	s.mu.Unlock()
+	return s.Notifier.CloseNotification(id)
+}
+
+// IsOpen returns true until the scope or the base Notifier is closed.
+func (s *NotificationScope) IsOpen() bool {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	return !closed && s.Notifier.IsOpen()
+}
+
+// Done returns a channel that is closed when the scope has been closed and its notifications cleaned up.
+func (s *NotificationScope) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close closes all notifications sent through the scope. Notifications already closed
+// by the user or the server are ignored. The base Notifier is left open.
+// It is safe to be called multiple times.
+func (s *NotificationScope) Close() error {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		ids := s.ids
+		s.ids = nil
+		s.mu.Unlock()
+
+		for id := range ids {
+			if err := s.Notifier.CloseNotification(id); err != nil && !IsNotFound(err) && s.err == nil {
+				s.err = err
+			}
+		}
+		close(s.done)
+	})
+	return s.err
+}
+
+// Shutdown closes the scope like Close. The base Notifier is left open.
+func (s *NotificationScope) Shutdown(ctx context.Context) error {
+	return s.Close()
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"fmt"
+)
+
+// ParseVersion parses Version on the form major.minor.patch.
+// Missing minor and patch components are returned as 0, and any trailing text
+// after the numbers, e.g. "-dev", is ignored. An error is only returned
+// if not even the major version can be parsed.
+func (si ServerInformation) ParseVersion() (major, minor, patch int, err error) {
+	return parseVersion(si.Version)
+}
+
+// AtLeast returns true if the server Version is at least major.minor.
+// Returns false if the version can not be parsed.
+func (si ServerInformation) AtLeast(major, minor int) bool {
+	return versionAtLeast(si.Version, major, minor)
+}
+
+// SpecVersionAtLeast returns true if the server claims compliance with at least spec version major.minor.
+// Returns false if the spec version can not be parsed.
+func (si ServerInformation) SpecVersionAtLeast(major, minor int) bool {
+	return versionAtLeast(si.SpecVersion, major, minor)
+}
+
+// String identifies the server, e.g. "dunst by knopwob v1.9.0 (spec v1.2)".
+func (si ServerInformation) String() string {
+	return fmt.Sprintf("%s by %s v%s (spec v%s)", si.Name, si.Vendor, si.Version, si.SpecVersion)
+}
+
+// IsUnknown returns true if all fields are empty, as returned alongside an error
+// from GetServerInformation.
+func (si ServerInformation) IsUnknown() bool {
+	return si == ServerInformation{}
+}
+
+func parseVersion(version string) (major, minor, patch int, err error) {
+	n, err := fmt.Sscanf(version, "%d.%d.%d", &major, &minor, &patch)
+	if n == 0 {
+		return 0, 0, 0, fmt.Errorf("error parsing version %q: %w", version, err)
+	}
+	return major, minor, patch, nil
+}
+
+func versionAtLeast(version string, major, minor int) bool {
+	gotMajor, gotMinor, _, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	if gotMajor != major {
+		return gotMajor > major
+	}
+	return gotMinor >= minor
+}
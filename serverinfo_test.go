@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerInformationParseVersion(t *testing.T) {
+	cases := []struct {
+		version             string
+		major, minor, patch int
+	}{
+		{"1.2.3", 1, 2, 3},
+		{"1.2.3-dev", 1, 2, 3},
+		{"1.2", 1, 2, 0},
+		{"3", 3, 0, 0},
+		{"40.rc", 40, 0, 0},
+	}
+	for _, c := range cases {
+		major, minor, patch, err := ServerInformation{Version: c.version}.ParseVersion()
+		require.NoError(t, err, c.version)
+		require.Equal(t, []int{c.major, c.minor, c.patch}, []int{major, minor, patch}, c.version)
+	}
+
+	_, _, _, err := ServerInformation{Version: "dev"}.ParseVersion()
+	require.Error(t, err)
+	_, _, _, err = ServerInformation{}.ParseVersion()
+	require.Error(t, err)
+}
+
+func TestServerInformationAtLeast(t *testing.T) {
+	si := ServerInformation{Version: "1.2.3-dev", SpecVersion: "1.2"}
+	require.True(t, si.AtLeast(1, 0))
+	require.True(t, si.AtLeast(1, 2))
+	require.True(t, si.AtLeast(0, 9))
+	require.False(t, si.AtLeast(1, 3))
+	require.False(t, si.AtLeast(2, 0))
+	require.True(t, si.SpecVersionAtLeast(1, 2))
+	require.False(t, si.SpecVersionAtLeast(1, 3))
+
+	require.True(t, ServerInformation{Version: "3"}.AtLeast(2, 5))
+	require.False(t, ServerInformation{Version: "unknown"}.AtLeast(0, 0))
+}
+
+func TestServerInformationString(t *testing.T) {
+	info := ServerInformation{Name: "dunst", Vendor: "knopwob", Version: "1.9.0", SpecVersion: "1.2"}
+	require.Equal(t, "dunst by knopwob v1.9.0 (spec v1.2)", info.String())
+	require.False(t, info.IsUnknown())
+	require.True(t, ServerInformation{}.IsUnknown())
+	require.False(t, ServerInformation{SpecVersion: "1.2"}.IsUnknown())
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// NewSessionBusNotifier creates a new Notifier connected to the session bus.
+//
+// By default the shared session bus connection from dbus.SessionBus() is used,
+// which is left open on Close(). See WithSessionBusPrivate to use a private connection instead.
+func NewSessionBusNotifier(opts ...option) (Notifier, error) {
+	n := newNotifier(opts...)
+
+	conn, err := n.connectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	if err := n.start(conn); err != nil {
+		if n.ownsConn {
+			_ = conn.Close()
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+// Conn returns the connection n makes calls on, for making other dbus calls on the same connection.
+// The connection must not be closed while n is in use, see IsConnectionOwned.
+func (n *notifier) Conn() *dbus.Conn {
+	return n.conn
+}
+
+// IsConnectionOwned returns true if n closes its connection on Close(), which is the case
+// for private connections opened by NewSessionBusNotifier. Connections passed to New are never owned.
+//
+// Wrappers are seen through if they implement Unwrap() Notifier, returning the Notifier they wrap,
+// as the wrappers of this package do. Returns false for other Notifier implementations of other packages.
+func IsConnectionOwned(n Notifier) bool {
+	for {
+		switch v := n.(type) {
+		case *notifier:
+			return v.ownsConn
+		case notifierWrapper:
+			n = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// notifierWrapper is implemented by Notifiers wrapping another Notifier.
+type notifierWrapper interface {
+	Unwrap() Notifier
+}
+
+// WithSessionBusPrivate makes NewSessionBusNotifier open, authenticate and use a private
+// connection to the session bus, instead of the shared connection from dbus.SessionBus().
+// It has no effect on New().
+//
+// A private connection does not share match rules or signal delivery with other users
+// of the shared connection in the same process, so notifications and their signals are
+// kept apart from other parts of the application. The cost is one extra socket.
+// The private connection is closed by Close().
+func WithSessionBusPrivate() option {
+	return func(n *notifier) {
+		n.sessionBusPrivate = true
+	}
+}
+
+// WithDialTimeout makes NewSessionBusNotifier fail with ErrDialTimeout when connecting to the session bus,
+// including authenticating and sending Hello, takes longer than d. It has no effect on New().
+//
+// A private connection from WithSessionBusPrivate is closed when it times out. The shared connection
+// keeps connecting in the background, as it is shared with the rest of the process, and is reused
+// by later calls once connected. d must not be negative. Without this option, or with d of 0,
+// connecting may block for as long as the bus does not respond.
+func WithDialTimeout(d time.Duration) option {
+	return func(n *notifier) {
+		n.dialTimeout = d
+	}
+}
+
+// GetDefaultSession opens a private connection to the session bus, and authenticates and sends Hello on it,
+// returning a connection ready to be passed to New.
+// The caller owns the connection and must close it when done, e.g. with defer conn.Close().
+func GetDefaultSession() (*dbus.Conn, error) {
+	return ConnectAndAuthenticate()
+}
+
+// ConnectOption configures ConnectAndAuthenticate.
+type ConnectOption func(*connectConfig)
+
+type connectConfig struct {
+	authMethods []dbus.Auth
+	socketPath  string
+	timeout     time.Duration
+}
+
+// WithAuthMethods authenticates with methods, instead of the default methods of dbus.Conn.Auth.
+func WithAuthMethods(methods []dbus.Auth) ConnectOption {
+	return func(c *connectConfig) {
+		c.authMethods = methods
+	}
+}
+
+// WithSocketPath connects to the bus listening on the unix socket at path,
+// instead of the session bus found in the environment.
+func WithSocketPath(path string) ConnectOption {
+	return func(c *connectConfig) {
+		c.socketPath = path
+	}
+}
+
+// WithConnectTimeout fails with ErrDialTimeout when authenticating and sending Hello take longer than d,
+// see WithDialTimeout. d must not be negative.
+func WithConnectTimeout(d time.Duration) ConnectOption {
+	return func(c *connectConfig) {
+		c.timeout = d
+	}
+}
+
+// ConnectAndAuthenticate opens a private connection to the session bus, authenticates and sends Hello on it,
+// returning a connection ready to be passed to New. Hello must not be sent again on the connection.
+// The caller owns the connection and must close it when done, e.g. with defer conn.Close().
+func ConnectAndAuthenticate(opts ...ConnectOption) (*dbus.Conn, error) {
+	config := connectConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.timeout < 0 {
+		return nil, fmt.Errorf("invalid connect timeout: %v", config.timeout)
+	}
+
+	var conn *dbus.Conn
+	var err error
+	if config.socketPath != "" {
+		conn, err = dbus.Dial("unix:path=" + dbus.EscapeBusAddressValue(config.socketPath))
+	} else {
+		conn, err = dbus.SessionBusPrivate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to session bus: %w", err)
+	}
+	// closing the connection aborts a pending Auth or Hello
+	err = runWithTimeout(config.timeout, func() error { return authenticate(conn, config.authMethods) }, func() { _ = conn.Close() })
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// authenticate authenticates with methods, or the defaults if nil, and sends Hello on conn.
+func authenticate(conn *dbus.Conn, methods []dbus.Auth) error {
+	if err := conn.Auth(methods); err != nil {
+		return fmt.Errorf("error authenticating to session bus: %w", err)
+	}
+	if err := conn.Hello(); err != nil {
+		return fmt.Errorf("error sending hello to session bus: %w", err)
+	}
+	return nil
+}
+
+// runWithTimeout runs f, and returns ErrDialTimeout if it does not return within timeout,
+// calling abort, unless nil, to make f return. A timeout of 0 waits for f.
+func runWithTimeout(timeout time.Duration, f func() error, abort func()) error {
+	if timeout == 0 {
+		return f()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		if abort != nil {
+			abort()
+		}
+		return ErrDialTimeout
+	}
+}
+
+// GetSharedSession returns the shared connection to the session bus from dbus.SessionBus().
+// The connection is shared by all users in the process, and should not be closed.
+func GetSharedSession() (*dbus.Conn, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to session bus: %w", err)
+	}
+	return conn, nil
+}
+
+func (n *notifier) connectSessionBus() (*dbus.Conn, error) {
+	if n.dialTimeout < 0 {
+		return nil, fmt.Errorf("invalid dial timeout: %v", n.dialTimeout)
+	}
+	if !n.sessionBusPrivate {
+		var conn *dbus.Conn
+		err := runWithTimeout(n.dialTimeout, func() error {
+			shared, err := GetSharedSession()
+			conn = shared
+			return err
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	conn, err := ConnectAndAuthenticate(WithConnectTimeout(n.dialTimeout))
+	if err != nil {
+		return nil, err
+	}
+	n.ownsConn = true
+	return conn, nil
+}
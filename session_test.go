@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	timeout := 50 * time.Millisecond
+	aborted := make(chan struct{})
+	slowAuth := func() error {
+		select {
+		case <-aborted:
+			return errors.New("connection closed")
+		case <-time.After(10 * time.Second):
+			return nil
+		}
+	}
+
+	start := time.Now()
+	err := runWithTimeout(timeout, slowAuth, func() { close(aborted) })
+	require.Equal(t, ErrDialTimeout, err)
+	require.True(t, time.Since(start) < 2*timeout, "took %v", time.Since(start))
+
+	errAuth := errors.New("auth failed")
+	require.Equal(t, errAuth, runWithTimeout(timeout, func() error { return errAuth }, nil))
+	require.NoError(t, runWithTimeout(0, func() error { return nil }, nil))
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// SignalWaiter sends notifications and waits until each of them has received a signal.
+//
+// Exactly one signal is counted per notification, whether it is ActionInvoked or NotificationClosed,
+// so servers emitting both, or duplicate signals, do not throw off the count.
+type SignalWaiter struct {
+	notifier Notifier
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewSignalWaiter creates a SignalWaiter sending with notifier.
+func NewSignalWaiter(notifier Notifier) *SignalWaiter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SignalWaiter{
+		notifier: notifier,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Add sends n, and registers it to be waited for by Wait.
+// Add must not be called concurrently with Wait.
+//
+// With the Notifier of New, no signal is missed. With other Notifiers, listening starts once n was sent,
+// so a signal received before then is missed, unless it is a NotificationClosed signal remembered by WaitForSignal.
+func (w *SignalWaiter) Add(n Notification) (uint32, error) {
+	id, wait, err := sendAndListen(w.notifier, n)
+	if err != nil {
+		return id, err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		_, _ = wait(w.ctx)
+	}()
+	return id, nil
+}
+
+// Wait blocks until all notifications added have received a signal.
+// If ctx is done first, the waiter stops listening for signals and the error of ctx is returned.
+func (w *SignalWaiter) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// signalListener is implemented by the Notifier of New, which can listen for the signals
+// of a notification before sending it.
+type signalListener interface {
+	IDReserver
+	listen(id uint32) (<-chan NotificationEvent, func())
+	splits(n Notification) bool
+}
+
+// sendAndListen sends n through notifier, and returns a function waiting for its first signal.
+// If notifier implements signalListener, listening starts before sending, so no signal is missed.
+// Otherwise listening starts once n was sent, like WaitForSignal.
+func sendAndListen(notifier Notifier, n Notification) (uint32, func(context.Context) (NotificationEvent, error), error) {
+	l, ok := notifier.(signalListener)
+	if !ok || l.splits(n) {
+		id, err := notifier.SendNotification(n)
+		return id, func(ctx context.Context) (NotificationEvent, error) {
+			return notifier.WaitForSignal(ctx, id)
+		}, err
+	}
+	reserved := l.ReserveID()
+	events, stop := l.listen(reserved)
+	id, err := l.SendWithID(reserved, n)
+	if err != nil {
+		stop()
+		return id, nil, err
+	}
+	return id, func(ctx context.Context) (NotificationEvent, error) {
+		defer stop()
+		select {
+		case e := <-events:
+			return e, nil
+		case <-ctx.Done():
+			return NotificationEvent{}, ctx.Err()
+		}
+	}, nil
+}
@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SoundKind tells whether a SoundVariant refers to a themed sound name or a sound file.
+type SoundKind int
+
+const (
+	// SoundKindName is a themeable named sound from the freedesktop.org sound naming spec.
+	SoundKindName SoundKind = iota
+	// SoundKindFile is a path to a sound file.
+	SoundKindFile
+)
+
+func (k SoundKind) String() string {
+	switch k {
+	case SoundKindName:
+		return "Name"
+	case SoundKindFile:
+		return "File"
+	default:
+		return "Other"
+	}
+}
+
+// SoundVariant is a sound Hint that remembers how it was created.
+// It can be passed directly to Notification.AddHint.
+type SoundVariant struct {
+	Hint
+	Kind SoundKind
+	// Value is the sound name or file path the hint was created from.
+	Value string
+}
+
+// HintSoundWithName plays a themeable named sound from the freedesktop.org sound naming spec.
+// See: http://0pointer.de/public/sound-naming-spec.html
+func HintSoundWithName(soundName string) SoundVariant {
+	return SoundVariant{
+		Hint: Hint{
+			ID:      "sound-name",
+			Variant: dbus.MakeVariant(soundName),
+		},
+		Kind:  SoundKindName,
+		Value: soundName,
+	}
+}
+
+// ErrInvalidSoundName is returned for sound names not following the sound naming spec.
+var ErrInvalidSoundName = errors.New("invalid sound name")
+
+// soundNameRegexp matches names of the sound naming spec: lowercase words separated by hyphens.
+var soundNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// ValidateSoundName checks that name follows the naming convention of the sound naming spec,
+// e.g. "message-new-instant". It returns an error wrapping ErrInvalidSoundName if not.
+// See: http://0pointer.de/public/sound-naming-spec.html
+func ValidateSoundName(name string) error {
+	if !soundNameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSoundName, name)
+	}
+	return nil
+}
+
+// HintSoundWithNameValidated is like HintSoundWithName, but returns an error if soundName
+// does not pass ValidateSoundName.
+func HintSoundWithNameValidated(soundName string) (SoundVariant, error) {
+	if err := ValidateSoundName(soundName); err != nil {
+		return SoundVariant{}, err
+	}
+	return HintSoundWithName(soundName), nil
+}
+
+// HintSoundWithFile plays the sound file at soundFilePath when the notification pops up.
+func HintSoundWithFile(soundFilePath string) SoundVariant {
+	return SoundVariant{
+		Hint: Hint{
+			ID:      "sound-file",
+			Variant: dbus.MakeVariant(soundFilePath),
+		},
+		Kind:  SoundKindFile,
+		Value: soundFilePath,
+	}
+}
+
+// Validate checks that the sound name is non-empty, or that the sound file exists.
+func (s SoundVariant) Validate() error {
+	switch s.Kind {
+	case SoundKindName:
+		if s.Value == "" {
+			return errors.New("sound name is empty")
+		}
+	case SoundKindFile:
+		if s.Value == "" {
+			return errors.New("sound file path is empty")
+		}
+		if _, err := os.Stat(s.Value); err != nil {
+			return fmt.Errorf("error reading sound file: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown sound kind: %v", s.Kind)
+	}
+	return nil
+}
+
+// SoundData encodes the "sound-data" hint (uqay): signed 16 bit little endian PCM samples,
+// interleaved for each channel.
+type SoundData struct {
+	SampleRate uint32 // u
+	Channels   uint16 // q
+	Data       []byte // ay
+}
+
+// HintSoundData embeds PCM audio in the notification, in the "sound-data" hint.
+// samples are interleaved for each of channels.
+//
+// The hint is not part of the spec, and very few servers implement it: most ignore it.
+// Prefer HintSoundWithName or HintSoundWithFile.
+func HintSoundData(sampleRate uint32, channels uint16, samples []int16) Hint {
+	data := make([]byte, 2*len(samples))
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(sample))
+	}
+	return Hint{
+		ID:      "sound-data",
+		Variant: dbus.MakeVariant(SoundData{SampleRate: sampleRate, Channels: channels, Data: data}),
+	}
+}
+
+// HintSoundDataFromFile reads a 16 bit PCM WAV file, and embeds its audio with HintSoundData.
+func HintSoundDataFromFile(path string) (Hint, error) {
+	wav, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Hint{}, fmt.Errorf("error reading sound file: %w", err)
+	}
+	data, err := parseWAV(wav)
+	if err != nil {
+		return Hint{}, fmt.Errorf("error reading sound file %v: %w", path, err)
+	}
+	return Hint{
+		ID:      "sound-data",
+		Variant: dbus.MakeVariant(data),
+	}, nil
+}
+
+// parseWAV extracts the samples of a RIFF WAVE file holding 16 bit PCM audio.
+func parseWAV(wav []byte) (SoundData, error) {
+	if len(wav) < 12 || !bytes.Equal(wav[0:4], []byte("RIFF")) || !bytes.Equal(wav[8:12], []byte("WAVE")) {
+		return SoundData{}, errors.New("not a WAV file")
+	}
+	var (
+		data      SoundData
+		hasFormat bool
+	)
+	for chunks := wav[12:]; len(chunks) >= 8; {
+		id := string(chunks[0:4])
+		size := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		chunks = chunks[8:]
+		if size > len(chunks) {
+			return SoundData{}, fmt.Errorf("truncated %q chunk", id)
+		}
+		chunk := chunks[:size]
+		// chunks are padded to an even size
+		if size%2 == 1 && size < len(chunks) {
+			size++
+		}
+		chunks = chunks[size:]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return SoundData{}, errors.New("truncated format chunk")
+			}
+			if format := binary.LittleEndian.Uint16(chunk[0:2]); format != 1 {
+				return SoundData{}, fmt.Errorf("unsupported audio format %d, only PCM is supported", format)
+			}
+			if bits := binary.LittleEndian.Uint16(chunk[14:16]); bits != 16 {
+				return SoundData{}, fmt.Errorf("unsupported %d bits per sample, only 16 is supported", bits)
+			}
+			data.Channels = binary.LittleEndian.Uint16(chunk[2:4])
+			data.SampleRate = binary.LittleEndian.Uint32(chunk[4:8])
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return SoundData{}, errors.New("data chunk before format chunk")
+			}
+			data.Data = chunk
+			return data, nil
+		}
+	}
+	return SoundData{}, errors.New("missing data chunk")
+}
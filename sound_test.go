@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoundVariant(t *testing.T) {
+	name := HintSoundWithName("message-new-instant")
+	require.Equal(t, SoundKindName, name.Kind)
+	require.Equal(t, "sound-name", name.ID)
+	require.NoError(t, name.Validate())
+	require.Error(t, HintSoundWithName("").Validate())
+
+	file := HintSoundWithFile("sound_test.go")
+	require.Equal(t, SoundKindFile, file.Kind)
+	require.Equal(t, "sound-file", file.ID)
+	require.NoError(t, file.Validate())
+	require.Error(t, HintSoundWithFile("does-not-exist.wav").Validate())
+
+	n := Notification{}
+	n.AddHint(file)
+	require.Equal(t, "sound_test.go", n.Hints["sound-file"].Value())
+}
+
+func TestValidateSoundName(t *testing.T) {
+	for _, name := range []string{"bell", "message-new-instant", "dialog-warning", "x11-bell"} {
+		require.NoError(t, ValidateSoundName(name), name)
+	}
+	for _, name := range []string{"", "Bell", "message-New", "-bell", "1bell", "bell_terminal", "bell terminal", "/usr/share/sounds/bell.oga"} {
+		err := ValidateSoundName(name)
+		require.Error(t, err, name)
+		require.True(t, errors.Is(err, ErrInvalidSoundName), name)
+	}
+
+	hint, err := HintSoundWithNameValidated("bell")
+	require.NoError(t, err)
+	require.Equal(t, HintSoundWithName("bell"), hint)
+
+	_, err = HintSoundWithNameValidated("Bell")
+	require.True(t, errors.Is(err, ErrInvalidSoundName))
+}
+
+func TestHintSoundData(t *testing.T) {
+	hint := HintSoundData(8000, 1, []int16{1, -1, 256})
+	require.Equal(t, "sound-data", hint.ID)
+	require.Equal(t, "(uqay)", hint.Variant.Signature().String())
+	require.Equal(t, SoundData{SampleRate: 8000, Channels: 1, Data: []byte{1, 0, 0xff, 0xff, 0, 1}}, hint.Variant.Value())
+}
+
+// wavFile builds a 16 bit PCM WAV file with an extra chunk of odd size before the data.
+func wavFile(sampleRate uint32, channels uint16, pcm []byte) []byte {
+	fmtChunk := &bytes.Buffer{}
+	_ = binary.Write(fmtChunk, binary.LittleEndian, struct {
+		Format, Channels       uint16
+		SampleRate, ByteRate   uint32
+		BlockAlign, BitsSample uint16
+	}{1, channels, sampleRate, sampleRate * uint32(channels) * 2, channels * 2, 16})
+
+	body := &bytes.Buffer{}
+	body.WriteString("WAVE")
+	for _, chunk := range []struct {
+		id   string
+		data []byte
+	}{{"fmt ", fmtChunk.Bytes()}, {"LIST", []byte{1, 2, 3}}, {"data", pcm}} {
+		body.WriteString(chunk.id)
+		_ = binary.Write(body, binary.LittleEndian, uint32(len(chunk.data)))
+		body.Write(chunk.data)
+		if len(chunk.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	wav := &bytes.Buffer{}
+	wav.WriteString("RIFF")
+	_ = binary.Write(wav, binary.LittleEndian, uint32(body.Len()))
+	wav.Write(body.Bytes())
+	return wav.Bytes()
+}
+
+func TestHintSoundDataFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify-sound")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bell.wav")
+	pcm := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	require.NoError(t, ioutil.WriteFile(path, wavFile(44100, 2, pcm), 0600))
+
+	hint, err := HintSoundDataFromFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "(uqay)", hint.Variant.Signature().String())
+	require.Equal(t, SoundData{SampleRate: 44100, Channels: 2, Data: pcm}, hint.Variant.Value())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a wav file"), 0600))
+	_, err = HintSoundDataFromFile(path)
+	require.Error(t, err)
+
+	_, err = HintSoundDataFromFile(filepath.Join(dir, "missing.wav"))
+	require.Error(t, err)
+}
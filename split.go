@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithBodySplitting makes SendNotification split bodies longer than maxBytes into several notifications,
+// see SendNotificationSplit. This is useful for tools showing long text, such as log lines.
+func WithBodySplitting(maxBytes int) option {
+	return func(n *notifier) {
+		n.bodySplitMax = maxBytes
+	}
+}
+
+// splits returns true if SendNotification splits note into several notifications.
+func (n *notifier) splits(note Notification) bool {
+	return n.bodySplitMax > 0 && len(note.Body) > n.bodySplitMax
+}
+
+// SendNotificationSplit sends note, split into several notifications if its body is longer than
+// the maximum set by WithBodySplitting, and returns the IDs of all notifications sent.
+//
+// The body is split after the last '.' or newline that fits, or at the maximum if there is none.
+// "(i/N)" is appended to the summary of each part. Only the first part replaces note.ReplacesID.
+// If a part fails to send, the IDs of the parts sent so far are returned with the error.
+func (n *notifier) SendNotificationSplit(note Notification) ([]uint32, error) {
+	if !n.splits(note) {
+		ctx, cancel := n.callContext()
+		defer cancel()
+		id, err := n.sendNotification(ctx, note)
+		if err != nil {
+			return nil, err
+		}
+		return []uint32{id}, nil
+	}
+
+	parts := splitBody(note.Body, n.bodySplitMax)
+	ids := make([]uint32, 0, len(parts))
+	for i, body := range parts {
+		part := note
+		part.Body = body
+		part.Summary = fmt.Sprintf("%s (%d/%d)", note.Summary, i+1, len(parts))
+		if i > 0 {
+			part.ReplacesID = 0
+		}
+		ctx, cancel := n.callContext()
+		id, err := n.sendNotification(ctx, part)
+		cancel()
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// splitBody splits body into parts of at most maxBytes bytes, preferring to split after a '.' or newline.
+// Whitespace at the start of a part is dropped. Multi-byte runes are never cut in half.
+func splitBody(body string, maxBytes int) []string {
+	var parts []string
+	for len(body) > maxBytes {
+		cut := strings.LastIndexAny(body[:maxBytes], ".\n") + 1
+		if cut == 0 {
+			cut = maxBytes
+			for cut > 0 && !utf8.RuneStart(body[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				// a single rune longer than maxBytes
+				_, cut = utf8.DecodeRuneInString(body)
+			}
+		}
+		parts = append(parts, body[:cut])
+		body = strings.TrimLeft(body[cut:], " \t\n")
+	}
+	if body != "" || len(parts) == 0 {
+		parts = append(parts, body)
+	}
+	return parts
+}
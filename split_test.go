@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBody(t *testing.T) {
+	require.Equal(t, []string{"short"}, splitBody("short", 10))
+	require.Equal(t, []string{""}, splitBody("", 10))
+
+	require.Equal(t,
+		[]string{"First one.", "Second one.", "Third."},
+		splitBody("First one. Second one. Third.", 12))
+	require.Equal(t,
+		[]string{"line one\n", "line two"},
+		splitBody("line one\nline two", 12))
+
+	// no boundary: cut at the maximum, never within a rune
+	require.Equal(t, []string{"abcd", "efgh", "ij"}, splitBody("abcdefghij", 4))
+	require.Equal(t, []string{"æ", "ø", "å"}, splitBody("æøå", 3))
+	require.Equal(t, []string{"æ", "ø"}, splitBody("æø", 1))
+
+	long := strings.Repeat("Sentence. ", 100)
+	for _, part := range splitBody(long, 64) {
+		require.LessOrEqual(t, len(part), 64)
+		require.True(t, strings.HasSuffix(strings.TrimSpace(part), "."), part)
+	}
+}
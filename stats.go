@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"sync/atomic"
+)
+
+// NotifierStats is a snapshot of the counters of a Notifier.
+type NotifierStats struct {
+	// SendCount is the number of notifications attempted sent, including failed attempts
+	SendCount uint64
+	// SendErrorCount is the number of notifications that failed to send
+	SendErrorCount uint64
+	// CloseCount is the number of calls to CloseNotification, including failed calls
+	CloseCount uint64
+	// CloseErrorCount is the number of calls to CloseNotification that failed
+	CloseErrorCount        uint64
+	ActionSignalsReceived  uint64
+	ClosedSignalsReceived  uint64
+	UnknownSignalsReceived uint64
+	// DroppedSignalCount is the number of signals dropped because the signal buffer was full.
+	// See WithSignalBufferSize, WithActionChannel and WithClosedChannel.
+	DroppedSignalCount uint64
+	// TimedOutHandlerCount is the number of signal handlers still running twice the timeout after being called.
+	// See WithHandlerTimeout.
+	TimedOutHandlerCount uint64
+	// PanicCount is the number of signal handlers that panicked. See WithOnPanic.
+	PanicCount uint64
+}
+
+// notifierStats holds the counters of a notifier, updated with sync/atomic.
+// Always allocated on its own, so the uint64 fields are 64-bit aligned also on 32-bit platforms.
+type notifierStats struct {
+	send           uint64
+	sendError      uint64
+	close          uint64
+	closeError     uint64
+	actionSignals  uint64
+	closedSignals  uint64
+	unknownSignals uint64
+	droppedSignals uint64
+	timedOut       uint64
+	panics         uint64
+}
+
+func (s *notifierStats) snapshot() NotifierStats {
+	return NotifierStats{
+		SendCount:              atomic.LoadUint64(&s.send),
+		SendErrorCount:         atomic.LoadUint64(&s.sendError),
+		CloseCount:             atomic.LoadUint64(&s.close),
+		CloseErrorCount:        atomic.LoadUint64(&s.closeError),
+		ActionSignalsReceived:  atomic.LoadUint64(&s.actionSignals),
+		ClosedSignalsReceived:  atomic.LoadUint64(&s.closedSignals),
+		UnknownSignalsReceived: atomic.LoadUint64(&s.unknownSignals),
+		DroppedSignalCount:     atomic.LoadUint64(&s.droppedSignals),
+		TimedOutHandlerCount:   atomic.LoadUint64(&s.timedOut),
+		PanicCount:             atomic.LoadUint64(&s.panics),
+	}
+}
+
+// Stats returns a snapshot of the counters of n.
+func (n *notifier) Stats() NotifierStats {
+	return n.stats.snapshot()
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+func TestRelaySignalsDropsWhenFull(t *testing.T) {
+	n := newNotifier(WithSignalBufferSize(1), WithLogger(discardLogger{}))
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		n.relaySignals(make(chan struct{}))
+	}()
+
+	// nothing reads the signal buffer, so only the first signal fits
+	for i := 0; i < 3; i++ {
+		n.intake <- &dbus.Signal{Name: dbusNotificationsInterface + "." + signalActionInvoked}
+	}
+	close(n.intake)
+	<-relayDone
+
+	received := 0
+	for range n.signal {
+		received++
+	}
+	require.Equal(t, 1, received)
+	require.EqualValues(t, 2, n.Stats().DroppedSignalCount)
+}
+
+func TestSignalBufferDefaultLossless(t *testing.T) {
+	n := newNotifier()
+	require.False(t, n.dropSignals)
+	require.Equal(t, n.signal, n.intake)
+	require.Equal(t, channelBufferSize, cap(n.signal))
+}
+
+func TestWithSignalBufferSizeInvalid(t *testing.T) {
+	_, err := New(nil, WithSignalBufferSize(0))
+	require.Error(t, err)
+}
+
+func TestWithHandlerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan uint32, 2)
+	n := newNotifier(
+		WithHandlerTimeout(20*time.Millisecond),
+		WithLogger(discardLogger{}),
+		WithOnAction(func(s *ActionInvokedSignal) {
+			if s.ID == 1 {
+				<-release
+			}
+			handled <- s.ID
+		}),
+	)
+	action := func(id uint32) *dbus.Signal {
+		return &dbus.Signal{Name: n.endpoint.member(signalActionInvoked), Body: []interface{}{id, "open"}}
+	}
+
+	// the stuck handler does not keep the next one from running
+	n.handleSignal(action(1))
+	n.handleSignal(action(2))
+	require.Equal(t, uint32(2), <-handled)
+
+	require.Eventually(t, func() bool {
+		return n.Stats().TimedOutHandlerCount == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	require.Equal(t, uint32(1), <-handled)
+}
+
+func TestHandlerPanicRecovered(t *testing.T) {
+	var recovered []interface{}
+	var actions []string
+	n := newNotifier(
+		WithLogger(discardLogger{}),
+		WithOnAction(func(s *ActionInvokedSignal) {
+			if s.ActionKey == "panic" {
+				panic("handler failed")
+			}
+			actions = append(actions, s.ActionKey)
+		}),
+		WithOnPanic(func(r interface{}) { recovered = append(recovered, r) }),
+	)
+	name := dbusNotificationsInterface + "." + signalActionInvoked
+
+	n.handleSignal(&dbus.Signal{Name: name, Body: []interface{}{uint32(1), "panic"}})
+	// later signals are still handled
+	n.handleSignal(&dbus.Signal{Name: name, Body: []interface{}{uint32(1), "open"}})
+
+	require.Equal(t, []interface{}{"handler failed"}, recovered)
+	require.Equal(t, []string{"open"}, actions)
+	require.EqualValues(t, 1, n.Stats().PanicCount)
+	require.EqualValues(t, 2, n.Stats().ActionSignalsReceived)
+}
+
+func TestSignalChannels(t *testing.T) {
+	actions := make(chan *ActionInvokedSignal, 1)
+	closed := make(chan *NotificationClosedSignal, 1)
+	n := newNotifier(WithLogger(discardLogger{}), WithActionChannel(actions), WithClosedChannel(closed))
+
+	for i := 0; i < 2; i++ {
+		n.handleSignal(&dbus.Signal{
+			Name: dbusNotificationsInterface + "." + signalActionInvoked,
+			Body: []interface{}{uint32(1), "open"},
+		})
+	}
+	n.handleSignal(&dbus.Signal{
+		Name: dbusNotificationsInterface + "." + signalNotificationClosed,
+		Body: []interface{}{uint32(1), uint32(ReasonDismissedByUser)},
+	})
+
+	require.Equal(t, &ActionInvokedSignal{ID: 1, ActionKey: "open"}, <-actions)
+	require.Equal(t, &NotificationClosedSignal{ID: 1, Reason: ReasonDismissedByUser}, <-closed)
+	// the second action did not fit the channel
+	require.EqualValues(t, 1, n.Stats().DroppedSignalCount)
+}
+
+func TestSignalContextHandlers(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+
+	var got []interface{}
+	n := newNotifier(
+		WithOnActionCtx(func(ctx context.Context, s *ActionInvokedSignal) {
+			got = append(got, ctx.Value(ctxKey{}), s.ActionKey)
+		}),
+		WithOnClosedCtx(func(ctx context.Context, s *NotificationClosedSignal) {
+			got = append(got, ctx.Value(ctxKey{}), s.Reason)
+		}),
+		// the context may be set after the handlers
+		WithSignalContext(ctx),
+	)
+	n.handleSignal(&dbus.Signal{
+		Name: dbusNotificationsInterface + "." + signalActionInvoked,
+		Body: []interface{}{uint32(1), "open"},
+	})
+	n.handleSignal(&dbus.Signal{
+		Name: dbusNotificationsInterface + "." + signalNotificationClosed,
+		Body: []interface{}{uint32(1), uint32(ReasonExpired)},
+	})
+	require.Equal(t, []interface{}{"request-1", "open", "request-1", ReasonExpired}, got)
+}
@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// NotificationTemplate renders notifications with a Summary and Body written as text/template templates,
+// e.g. "CPU: {{.CPU}}%", and sends them through a Notifier.
+//
+// All other fields of the notification are sent as is.
+type NotificationTemplate struct {
+	notifier Notifier
+	note     Notification
+	summary  *template.Template
+	body     *template.Template
+}
+
+// NewNotificationTemplate parses the Summary and Body of n as templates, to be sent through notifier.
+func NewNotificationTemplate(notifier Notifier, n Notification) (*NotificationTemplate, error) {
+	summary, err := template.New("summary").Parse(n.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing summary template: %w", err)
+	}
+	body, err := template.New("body").Parse(n.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing body template: %w", err)
+	}
+	return &NotificationTemplate{
+		notifier: notifier,
+		note:     n.Clone(),
+		summary:  summary,
+		body:     body,
+	}, nil
+}
+
+// Render executes the templates with data, and returns the notification to send.
+// If data is a func() interface{}, it is called and its result is used instead,
+// allowing data that changes between renders.
+func (t *NotificationTemplate) Render(data interface{}) (Notification, error) {
+	if f, ok := data.(func() interface{}); ok {
+		data = f()
+	}
+	n := t.note.Clone()
+	sb := &strings.Builder{}
+	if err := t.summary.Execute(sb, data); err != nil {
+		return Notification{}, fmt.Errorf("error rendering summary: %w", err)
+	}
+	n.Summary = sb.String()
+	sb.Reset()
+	if err := t.body.Execute(sb, data); err != nil {
+		return Notification{}, fmt.Errorf("error rendering body: %w", err)
+	}
+	n.Body = sb.String()
+	return n, nil
+}
+
+// BindData binds data to the template, for sending with Refresh. See Render for data.
+func (t *NotificationTemplate) BindData(data interface{}) *ScheduledNotification {
+	return &ScheduledNotification{
+		template: t,
+		data:     data,
+	}
+}
+
+// ScheduledNotification is a NotificationTemplate bound to data, created by NotificationTemplate.BindData.
+type ScheduledNotification struct {
+	template *NotificationTemplate
+	data     interface{}
+
+	mu  sync.Mutex
+	id  uint32
+	err error
+}
+
+// Refresh renders and sends the notification right away, then renders it again and replaces the shown notification
+// every period, until the returned stop function is called, the notification is closed, or rendering or sending fails.
+// The error stopping the refresh is available from Err.
+//
+// The stop function waits for the refresh to stop, and is safe to be called multiple times.
+// It does not close the notification.
+func (s *ScheduledNotification) Refresh(period time.Duration) (func(), error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("notify: refresh period must be positive: %v", period)
+	}
+	n, err := s.template.Render(s.data)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.template.notifier.SendNotification(n)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.id, s.err = id, nil
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		for {
+			e, err := s.template.notifier.WaitForSignal(ctx, id)
+			if err != nil || e.IsClosed() {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := s.refresh(id); err != nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+				return
+			}
+		}
+	}()
+
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+		})
+	}, nil
+}
+
+// refresh renders the notification and replaces the notification with id.
+func (s *ScheduledNotification) refresh(id uint32) error {
+	n, err := s.template.Render(s.data)
+	if err != nil {
+		return err
+	}
+	n.ReplacesID = id
+	_, err = s.template.notifier.SendNotification(n)
+	return err
+}
+
+// ID returns the ID of the notification sent by Refresh, or 0 if it was not sent.
+func (s *ScheduledNotification) ID() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Err returns the error that stopped the last Refresh, or nil.
+func (s *ScheduledNotification) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
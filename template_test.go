@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationTemplateRender(t *testing.T) {
+	tmpl, err := NewNotificationTemplate(nil, Notification{AppName: "monitor", Summary: "CPU {{.CPU}}%", Body: "{{.Host}}"})
+	require.NoError(t, err)
+
+	n, err := tmpl.Render(map[string]interface{}{"CPU": 42, "Host": "box"})
+	require.NoError(t, err)
+	require.Equal(t, "monitor", n.AppName)
+	require.Equal(t, "CPU 42%", n.Summary)
+	require.Equal(t, "box", n.Body)
+
+	count := 0
+	data := func() interface{} {
+		count++
+		return map[string]interface{}{"CPU": count}
+	}
+	n, err = tmpl.Render(data)
+	require.NoError(t, err)
+	require.Equal(t, "CPU 1%", n.Summary)
+	n, err = tmpl.Render(data)
+	require.NoError(t, err)
+	require.Equal(t, "CPU 2%", n.Summary)
+
+	_, err = NewNotificationTemplate(nil, Notification{Summary: "{{.Broken"})
+	require.Error(t, err)
+}
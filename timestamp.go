@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	timestampHintID      = "timestamp"
+	dunstTimestampHintID = "x-dunst-timestamp"
+)
+
+// HintTimestamp sets the "timestamp" hint to t as Unix seconds, for servers that show
+// when a notification happened rather than when it was received, e.g. when an email arrived.
+// The hint is not part of version 1.2 of the spec, and few servers support it.
+// Times outside the range of uint32 Unix seconds are clamped to it.
+func HintTimestamp(t time.Time) Hint {
+	return Hint{
+		ID:      timestampHintID,
+		Variant: dbus.MakeVariant(unixSeconds(t)),
+	}
+}
+
+// HintDunstTimestamp sets the timestamp like HintTimestamp, with the "x-dunst-timestamp" hint of Dunst.
+// Add both hints to reach servers supporting either.
+func HintDunstTimestamp(t time.Time) Hint {
+	return Hint{
+		ID:      dunstTimestampHintID,
+		Variant: dbus.MakeVariant(unixSeconds(t)),
+	}
+}
+
+// GetTimestamp reads the timestamp of n from the hint of HintTimestamp, or else from the hint of
+// HintDunstTimestamp. Returns the zero time and false if neither is set, or the hint is not a uint32.
+func GetTimestamp(n Notification) (time.Time, bool) {
+	for _, id := range []string{timestampHintID, dunstTimestampHintID} {
+		variant, ok := n.Hints[id]
+		if !ok {
+			continue
+		}
+		if seconds, ok := variant.Value().(uint32); ok {
+			return time.Unix(int64(seconds), 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// unixSeconds returns t as uint32 Unix seconds, clamped to the range of uint32.
+func unixSeconds(t time.Time) uint32 {
+	seconds := t.Unix()
+	switch {
+	case seconds < 0:
+		return 0
+	case seconds > int64(^uint32(0)):
+		return ^uint32(0)
+	default:
+		return uint32(seconds)
+	}
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampHints(t *testing.T) {
+	arrived := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	n := Notification{Summary: "mail"}
+	_, ok := GetTimestamp(n)
+	require.False(t, ok)
+
+	n.AddHint(HintDunstTimestamp(arrived))
+	require.Equal(t, uint32(arrived.Unix()), n.Hints["x-dunst-timestamp"].Value())
+	got, ok := GetTimestamp(n)
+	require.True(t, ok)
+	require.True(t, arrived.Equal(got))
+
+	// the spec key is read first
+	later := arrived.Add(time.Hour)
+	n.AddHint(HintTimestamp(later))
+	got, ok = GetTimestamp(n)
+	require.True(t, ok)
+	require.True(t, later.Equal(got))
+	require.NoError(t, n.Validate())
+
+	n.Hints["timestamp"] = dbus.MakeVariant("yesterday")
+	n.Hints["x-dunst-timestamp"] = dbus.MakeVariant(int64(1))
+	_, ok = GetTimestamp(n)
+	require.False(t, ok)
+
+	require.Equal(t, uint32(0), unixSeconds(time.Unix(-1, 0)))
+}
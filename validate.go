@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ValidationError is returned by Validate when a Notification would be rejected or misbehave when sent.
+type ValidationError struct {
+	// Field is the name of the offending field of Notification
+	Field string
+	// Reason describes what is wrong with the field
+	Reason string
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("notify: invalid notification: %v: %v", e.Field, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationWarning is returned by Validate when a Notification can be sent,
+// but is likely to not display as intended, e.g. because of silent truncation by the server.
+type ValidationWarning struct {
+	// Field is the name of the offending field of Notification
+	Field string
+	// Reason describes what is wrong with the field
+	Reason string
+}
+
+func (w *ValidationWarning) Error() string {
+	return fmt.Sprintf("notify: notification warning: %v: %v", w.Field, w.Reason)
+}
+
+// The spec does not limit the length of summary and body, but many servers silently
+// truncate them. These are practical limits that are known to display on most servers.
+const (
+	MaxSummaryBytes = 128
+	MaxBodyBytes    = 4096
+)
+
+// maxSummaryBytes and maxBodyBytes are the limits checked by Validate, overridable in tests.
+var (
+	maxSummaryBytes = MaxSummaryBytes
+	maxBodyBytes    = MaxBodyBytes
+)
+
+// SummaryByteLen returns the length of Summary in bytes.
+func (n Notification) SummaryByteLen() int {
+	return len(n.Summary)
+}
+
+// BodyByteLen returns the length of Body in bytes.
+func (n Notification) BodyByteLen() int {
+	return len(n.Body)
+}
+
+// ErrEmptyActionKey is returned when validating an Action without a Key.
+var ErrEmptyActionKey = errors.New("notify: action key must not be empty")
+
+// ErrEmptyActionLabel is returned when validating an Action without a Label.
+var ErrEmptyActionLabel = errors.New("notify: action label must not be empty")
+
+// Validate checks that a has both a Key and a Label.
+// The spec requires keys to be non-empty, and some servers misbehave on empty keys.
+func (a Action) Validate() error {
+	if a.Key == "" {
+		return ErrEmptyActionKey
+	}
+	if a.Label == "" {
+		return ErrEmptyActionLabel
+	}
+	return nil
+}
+
+// ValidateActions validates each of actions, returning an error identifying the first invalid action.
+func ValidateActions(actions []Action) error {
+	for i, a := range actions {
+		if err := a.Validate(); err != nil {
+			return fmt.Errorf("action %d (key %q): %w", i, a.Key, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks n for problems before it is sent.
+// It returns a *ValidationError describing the first problem found.
+// If there are no errors, but n is likely to not display as intended,
+// a *ValidationWarning is returned. Use errors.As to tell them apart.
+//
+// Validate checks that Summary is set, and that ExpireTimeout is either a positive whole number
+// of milliseconds that fits the wire format, ExpireTimeoutNever or ExpireTimeoutSetByNotificationServer,
+// that all Actions are valid, and that Hints have the types of BuiltinRegistry.
+// It warns when Summary or Body are longer than MaxSummaryBytes and MaxBodyBytes,
+// when EstimateWireSize exceeds MaxWireSizeBytes, and for hints unknown to BuiltinRegistry.
+//
+// Note that a notification passing Validate is never empty, but a notification that is not empty
+// may still fail validation, e.g. when only Body is set. See IsEmpty.
+func (n Notification) Validate() error {
+	if n.Summary == "" {
+		return &ValidationError{Field: "Summary", Reason: "must not be empty"}
+	}
+	if n.ExpireTimeout < ExpireTimeoutSetByNotificationServer {
+		return &ValidationError{Field: "ExpireTimeout", Reason: fmt.Sprintf("must not be negative, got %v", n.ExpireTimeout)}
+	}
+	if n.ExpireTimeout%time.Millisecond != 0 {
+		// sent in whole milliseconds, so e.g. 500µs would be sent as 0, which means never expire
+		return &ValidationError{Field: "ExpireTimeout", Reason: fmt.Sprintf("must be a whole number of milliseconds, got %v", n.ExpireTimeout)}
+	}
+	if n.ExpireTimeout > math.MaxInt32*time.Millisecond {
+		return &ValidationError{Field: "ExpireTimeout", Reason: fmt.Sprintf("exceeds maximum of %v", math.MaxInt32*time.Millisecond)}
+	}
+	if n.Urgency != nil && !n.Urgency.IsKnown() {
+		return &ValidationError{Field: "Urgency", Reason: fmt.Sprintf("unknown urgency %d", *n.Urgency)}
+	}
+	for i, a := range n.Actions {
+		if err := a.Validate(); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("Actions[%d]", i), Reason: err.Error(), Err: err}
+		}
+	}
+	hintErr := builtinRegistry.Validate(n.Hints)
+	var validationErr *ValidationError
+	if errors.As(hintErr, &validationErr) {
+		return hintErr
+	}
+
+	if n.SummaryByteLen() > maxSummaryBytes {
+		return &ValidationWarning{Field: "Summary", Reason: fmt.Sprintf("%d bytes exceeds %d bytes and may be truncated", n.SummaryByteLen(), maxSummaryBytes)}
+	}
+	if n.BodyByteLen() > maxBodyBytes {
+		return &ValidationWarning{Field: "Body", Reason: fmt.Sprintf("%d bytes exceeds %d bytes and may be truncated", n.BodyByteLen(), maxBodyBytes)}
+	}
+	if size := EstimateWireSize(n); size > MaxWireSizeBytes {
+		return &ValidationWarning{Field: "Hints", Reason: fmt.Sprintf("estimated message size of %d bytes exceeds %d bytes and may be rejected by the bus", size, MaxWireSizeBytes)}
+	}
+	return hintErr
+}
+
+// IsEmpty returns true when no fields of n are set, meaning it is the zero value
+// apart from ReplacesID. Use it to skip sending conditionally built notifications:
+//
+//	if !n.IsEmpty() {
+//		notifier.SendNotification(n)
+//	}
+//
+// IsEmpty is not the inverse of Validate: a notification that is not empty can still fail Validate.
+func (n Notification) IsEmpty() bool {
+	return n.Summary == "" &&
+		n.Body == "" &&
+		n.AppName == "" &&
+		n.AppIcon == "" &&
+		len(n.Actions) == 0 &&
+		len(n.Hints) == 0 &&
+		n.Urgency == nil &&
+		n.ExpireTimeout == 0
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsEmpty(t *testing.T) {
+	require.True(t, Notification{}.IsEmpty())
+	require.True(t, Notification{Hints: map[string]dbus.Variant{}}.IsEmpty())
+	require.False(t, Notification{Body: "body"}.IsEmpty())
+	require.False(t, Notification{ExpireTimeout: time.Second}.IsEmpty())
+
+	n := Notification{}
+	n.SetUrgency(UrgencyLow)
+	require.False(t, n.IsEmpty())
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Notification{Summary: "summary"}.Validate())
+	require.NoError(t, Notification{Summary: "summary", ExpireTimeout: ExpireTimeoutSetByNotificationServer}.Validate())
+
+	var verr *ValidationError
+	err := Notification{Body: "body only"}.Validate()
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, "Summary", verr.Field)
+
+	err = Notification{Summary: "summary", ExpireTimeout: -time.Second}.Validate()
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, "ExpireTimeout", verr.Field)
+	// these would be sent as 0 milliseconds, which means never expire
+	for _, timeout := range []time.Duration{time.Microsecond, -time.Microsecond, 1500 * time.Microsecond} {
+		err = Notification{Summary: "summary", ExpireTimeout: timeout}.Validate()
+		require.True(t, errors.As(err, &verr), "%v", timeout)
+		require.Equal(t, "ExpireTimeout", verr.Field)
+	}
+}
+
+func TestValidateWarnsOnLength(t *testing.T) {
+	defer func(summary, body int) {
+		maxSummaryBytes, maxBodyBytes = summary, body
+	}(maxSummaryBytes, maxBodyBytes)
+	maxSummaryBytes, maxBodyBytes = 4, 8
+
+	n := Notification{Summary: "ok", Body: "body"}
+	require.NoError(t, n.Validate())
+	require.Equal(t, 2, n.SummaryByteLen())
+	require.Equal(t, 4, n.BodyByteLen())
+
+	var warning *ValidationWarning
+	err := Notification{Summary: "too long"}.Validate()
+	require.True(t, errors.As(err, &warning))
+	require.Equal(t, "Summary", warning.Field)
+
+	err = Notification{Summary: "ok", Body: "much too long"}.Validate()
+	require.True(t, errors.As(err, &warning))
+	require.Equal(t, "Body", warning.Field)
+
+	// errors take precedence over warnings
+	var verr *ValidationError
+	err = Notification{Body: "much too long"}.Validate()
+	require.True(t, errors.As(err, &verr))
+}
+
+func TestValidateActions(t *testing.T) {
+	require.NoError(t, Action{Key: "open", Label: "Open"}.Validate())
+	require.Equal(t, ErrEmptyActionKey, Action{Label: "Open"}.Validate())
+	require.Equal(t, ErrEmptyActionLabel, Action{Key: "open"}.Validate())
+
+	actions := []Action{{Key: "open", Label: "Open"}, {Key: "", Label: "Broken"}}
+	err := ValidateActions(actions)
+	require.True(t, errors.Is(err, ErrEmptyActionKey))
+	require.Contains(t, err.Error(), "action 1")
+
+	var verr *ValidationError
+	err = Notification{Summary: "summary", Actions: actions}.Validate()
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, "Actions[1]", verr.Field)
+	require.True(t, errors.Is(err, ErrEmptyActionKey))
+}
+
+func TestValidateHintTypes(t *testing.T) {
+	n := Notification{Summary: "summary"}
+	n.AddHint(HintFromCategory(CategoryIM))
+	n.AddHint(HintUrgency(UrgencyLow))
+	n.AddHint(HintProgress(50))
+	n.AddHint(Hint{ID: "x-vendor-custom", Variant: dbus.MakeVariant(1.5)})
+	require.NoError(t, n.Validate())
+
+	var verr *ValidationError
+	n.Hints["category"] = dbus.MakeVariant(true)
+	err := n.Validate()
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, `Hints["category"]`, verr.Field)
+
+	var warning *ValidationWarning
+	n.Hints["category"] = dbus.MakeVariant(string(CategoryIM))
+	n.Hints["unknown"] = dbus.MakeVariant("value")
+	err = n.Validate()
+	require.True(t, errors.As(err, &warning))
+	require.Equal(t, `Hints["unknown"]`, warning.Field)
+}
+
+func TestHintRegistry(t *testing.T) {
+	r := NewHintRegistry()
+	r.Register("count", "u", "number of items")
+	require.NoError(t, r.Validate(map[string]dbus.Variant{"count": dbus.MakeVariant(uint32(3))}))
+	require.Error(t, r.Validate(map[string]dbus.Variant{"count": dbus.MakeVariant(int32(3))}))
+	require.NoError(t, r.Validate(nil))
+
+	// the builtin registry is not shared
+	BuiltinRegistry().Register("category", "b", "changed")
+	require.NoError(t, builtinRegistry.Validate(map[string]dbus.Variant{"category": dbus.MakeVariant("im")}))
+}
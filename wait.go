@@ -0,0 +1,374 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// closeSignalTimeout is how long SendAndWaitForClose waits for the NotificationClosed signal
+// after closing a notification because its context expired.
+const closeSignalTimeout = 2 * time.Second
+
+// recentClosedSize is the number of NotificationClosed signals remembered without a listener,
+// so SendAndWaitForClose can pick up a signal arriving before it starts listening.
+const recentClosedSize = 32
+
+// SendAndWaitForClose sends note and blocks until a NotificationClosed signal is received for it.
+// note is sent with ctx, rather than the context of WithContext.
+//
+// If ctx is done before the notification is closed, the notification is closed with CloseNotification,
+// and the resulting signal with ReasonClosedByCall is returned.
+// If the server does not emit that signal in time, a signal with ReasonClosedByCall is returned
+// together with the error of ctx.
+func (n *notifier) SendAndWaitForClose(ctx context.Context, note Notification) (NotificationClosedSignal, error) {
+	if note.ReplacesID != 0 {
+		// a signal remembered for the replaced notification does not belong to the new one
+		n.waitersMu.Lock()
+		n.forgetRecentClosed(note.ReplacesID)
+		n.waitersMu.Unlock()
+	}
+	id, err := n.sendNotification(ctx, note)
+	if err != nil {
+		return NotificationClosedSignal{}, err
+	}
+
+	// the notification may have been closed before we listen for it, so check recent signals first.
+	n.waitersMu.Lock()
+	if s, ok := n.recentClosed[id]; ok {
+		n.forgetRecentClosed(id)
+		n.waitersMu.Unlock()
+		return *s, nil
+	}
+	closed := n.addCloseWaiter(id)
+	n.waitersMu.Unlock()
+	defer n.removeCloseWaiter(id, closed)
+
+	select {
+	case s := <-closed:
+		return *s, nil
+	case <-ctx.Done():
+	}
+
+	// clean up after ourselves: this produces a NotificationClosed signal with ReasonClosedByCall
+	closeErr := n.CloseNotification(id)
+	if closeErr != nil && !IsNotFound(closeErr) {
+		select {
+		case s := <-closed:
+			return *s, nil
+		default:
+			return NotificationClosedSignal{}, closeErr
+		}
+	}
+	// if not found, it was closed by someone else at the same time, so its signal is on the way.
+
+	timer := time.NewTimer(closeSignalTimeout)
+	defer timer.Stop()
+	select {
+	case s := <-closed:
+		return *s, nil
+	case <-timer.C:
+		if closeErr != nil {
+			return NotificationClosedSignal{}, closeErr
+		}
+		return NotificationClosedSignal{ID: id, Reason: ReasonClosedByCall}, ctx.Err()
+	}
+}
+
+// CloseNotificationSync closes the notification with id, and blocks until the resulting
+// NotificationClosed signal is received, which is then returned.
+// This makes sure signal handlers have run for the closed notification when it returns.
+//
+// If the signal is not received before ctx is done, the error of ctx is returned.
+func (n *notifier) CloseNotificationSync(ctx context.Context, id uint32) (NotificationClosedSignal, error) {
+	if id == 0 {
+		return NotificationClosedSignal{}, ErrInvalidNotificationID
+	}
+	n.waitersMu.Lock()
+	closed := n.addCloseWaiter(id)
+	n.waitersMu.Unlock()
+	defer n.removeCloseWaiter(id, closed)
+
+	if err := n.closeNotification(ctx, id); err != nil {
+		return NotificationClosedSignal{}, err
+	}
+
+	select {
+	case s := <-closed:
+		return *s, nil
+	case <-ctx.Done():
+		return NotificationClosedSignal{}, ctx.Err()
+	}
+}
+
+// NotificationEvent is a signal received for a notification: either Closed or Action is set.
+type NotificationEvent struct {
+	// ID of the notification the signal was received for
+	ID uint32
+	// Closed is set for a NotificationClosed signal
+	Closed *NotificationClosedSignal
+	// Action is set for an ActionInvoked signal
+	Action *ActionInvokedSignal
+}
+
+// IsClosed returns true if the event is a NotificationClosed signal.
+func (e NotificationEvent) IsClosed() bool {
+	return e.Closed != nil
+}
+
+// IsAction returns true if the event is an ActionInvoked signal.
+func (e NotificationEvent) IsAction() bool {
+	return e.Action != nil
+}
+
+// WaitForSignal blocks until an ActionInvoked or NotificationClosed signal is received for the
+// notification with id, and returns it. If the notification was closed shortly before,
+// the remembered NotificationClosed signal is returned right away.
+//
+// If ctx is done first, the error of ctx is returned.
+func (n *notifier) WaitForSignal(ctx context.Context, id uint32) (NotificationEvent, error) {
+	if id == 0 {
+		return NotificationEvent{}, ErrInvalidNotificationID
+	}
+	events, stop := n.listen(id)
+	defer stop()
+
+	select {
+	case e := <-events:
+		return e, nil
+	case <-ctx.Done():
+		return NotificationEvent{}, ctx.Err()
+	}
+}
+
+// listen registers a one-shot listener for the next signal of id, like WaitForSignal without blocking.
+// The returned function unregisters the listener, and must be called once done listening.
+func (n *notifier) listen(id uint32) (<-chan NotificationEvent, func()) {
+	ch := make(chan NotificationEvent, 1)
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	if s, ok := n.recentClosed[id]; ok {
+		ch <- NotificationEvent{ID: id, Closed: s}
+		return ch, func() {}
+	}
+	n.eventWaiters[id] = append(n.eventWaiters[id], ch)
+	n.trackWaiter(id)
+	return ch, func() { n.removeEventWaiter(id, ch) }
+}
+
+// removeEventWaiter removes ch from the event listeners of id, if it is still registered.
+func (n *notifier) removeEventWaiter(id uint32, ch chan NotificationEvent) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	waiters := n.eventWaiters[id]
+	for i := range waiters {
+		if waiters[i] == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(n.eventWaiters, id)
+		n.untrackWaiter(id)
+	} else {
+		n.eventWaiters[id] = waiters
+	}
+}
+
+// deliverEvent hands e to all event listeners of its ID, and of the ID reserved for it with ReserveID,
+// and unregisters them. Caller must hold n.waitersMu.
+func (n *notifier) deliverEvent(e NotificationEvent) {
+	if n.sendsWithID > 0 {
+		n.sentEvents = append(n.sentEvents, e)
+	}
+	n.deliverEventTo(e.ID, e)
+	n.deliverReserved(e)
+}
+
+// deliverEventTo hands e to all event listeners of id, and unregisters them.
+// Caller must hold n.waitersMu.
+func (n *notifier) deliverEventTo(id uint32, e NotificationEvent) {
+	for _, ch := range n.eventWaiters[id] {
+		ch <- e
+	}
+	delete(n.eventWaiters, id)
+}
+
+// deliverAction hands the signal to all event listeners waiting for it.
+func (n *notifier) deliverAction(s *ActionInvokedSignal) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	n.deliverEvent(NotificationEvent{ID: s.ID, Action: s})
+	n.untrackWaiter(s.ID)
+}
+
+// addCloseWaiter registers a one-shot listener for the NotificationClosed signal of id.
+// Caller must hold n.waitersMu.
+func (n *notifier) addCloseWaiter(id uint32) chan *NotificationClosedSignal {
+	ch := make(chan *NotificationClosedSignal, 1)
+	n.closeWaiters[id] = append(n.closeWaiters[id], ch)
+	n.trackWaiter(id)
+	return ch
+}
+
+// removeCloseWaiter removes ch from the listeners of id, if it is still registered.
+func (n *notifier) removeCloseWaiter(id uint32, ch chan *NotificationClosedSignal) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	waiters := n.closeWaiters[id]
+	for i := range waiters {
+		if waiters[i] == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(n.closeWaiters, id)
+		n.untrackWaiter(id)
+	} else {
+		n.closeWaiters[id] = waiters
+	}
+}
+
+// deliverClosed hands the signal to all listeners waiting for it, and unregisters them.
+// Without listeners from SendAndWaitForClose or CloseNotificationSync, the signal is remembered in recentClosed.
+func (n *notifier) deliverClosed(s *NotificationClosedSignal) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	n.deliverEvent(NotificationEvent{ID: s.ID, Closed: s})
+	n.untrackWaiter(s.ID)
+	if _, ok := n.closeWaiters[s.ID]; !ok {
+		n.rememberRecentClosed(s)
+		return
+	}
+	n.deliverCloseWaiters(s)
+}
+
+// deliverCloseWaiters hands s to all close listeners of its ID, and unregisters them.
+// Caller must hold n.waitersMu.
+func (n *notifier) deliverCloseWaiters(s *NotificationClosedSignal) {
+	for _, ch := range n.closeWaiters[s.ID] {
+		ch <- s
+	}
+	delete(n.closeWaiters, s.ID)
+	n.untrackWaiter(s.ID)
+}
+
+// rememberRecentClosed remembers s, forgetting the oldest signal if more than recentClosedSize are remembered.
+// Caller must hold n.waitersMu.
+func (n *notifier) rememberRecentClosed(s *NotificationClosedSignal) {
+	if _, ok := n.recentClosed[s.ID]; !ok {
+		n.recentClosedOrder = append(n.recentClosedOrder, s.ID)
+	}
+	n.recentClosed[s.ID] = s
+	n.closedSeq++
+	n.recentClosedSeq[s.ID] = n.closedSeq
+	if len(n.recentClosedOrder) > recentClosedSize {
+		delete(n.recentClosed, n.recentClosedOrder[0])
+		delete(n.recentClosedSeq, n.recentClosedOrder[0])
+		n.recentClosedOrder = n.recentClosedOrder[1:]
+	}
+}
+
+// forgetStaleClosed forgets a signal for id remembered before sequence number seq,
+// as a notification sent with id afterwards, by a server reusing IDs, is not closed yet.
+// Signals for id remembered after seq, while the notification was being sent, are kept.
+func (n *notifier) forgetStaleClosed(id uint32, seq uint64) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	if remembered, ok := n.recentClosedSeq[id]; ok && remembered <= seq {
+		n.forgetRecentClosed(id)
+	}
+}
+
+// forgetRecentClosed forgets a remembered signal for id.
+// Caller must hold n.waitersMu.
+func (n *notifier) forgetRecentClosed(id uint32) {
+	if _, ok := n.recentClosed[id]; !ok {
+		return
+	}
+	delete(n.recentClosed, id)
+	delete(n.recentClosedSeq, id)
+	for i, recent := range n.recentClosedOrder {
+		if recent == id {
+			n.recentClosedOrder = append(n.recentClosedOrder[:i], n.recentClosedOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// WithListenerTTL evicts listeners of SendAndWaitForClose, CloseNotificationSync and WaitForSignal
+// still waiting for a notification d after the first of them started listening.
+// Evicted listeners receive a NotificationClosed signal with ReasonUnknown, as if the notification was closed.
+//
+// This keeps listeners waiting with a context that is never done from piling up, when the server never emits
+// the NotificationClosed signal. Listeners are checked every d/2, but at most once per millisecond,
+// and d must not be negative.
+// Without this option, or with d of 0, listeners wait until the signal is received or their context is done.
+func WithListenerTTL(d time.Duration) option {
+	return func(n *notifier) {
+		n.listenerTTL = d
+	}
+}
+
+// trackWaiter records when listening for id started, unless a listener for id already exists.
+// Caller must hold n.waitersMu.
+func (n *notifier) trackWaiter(id uint32) {
+	if _, ok := n.waiterSince[id]; !ok {
+		n.waiterSince[id] = time.Now()
+	}
+}
+
+// untrackWaiter forgets when listening for id started, once no listeners for id remain.
+// Caller must hold n.waitersMu.
+func (n *notifier) untrackWaiter(id uint32) {
+	if len(n.closeWaiters[id]) == 0 && len(n.eventWaiters[id]) == 0 {
+		delete(n.waiterSince, id)
+	}
+}
+
+// minExpiryInterval is the shortest interval expired entries are checked at, however short their TTL.
+const minExpiryInterval = time.Millisecond
+
+// expiryInterval returns the interval to check for entries expired after ttl at: half of ttl,
+// but no shorter than minExpiryInterval, which also keeps tiny TTLs from making a ticker panic.
+func expiryInterval(ttl time.Duration) time.Duration {
+	if interval := ttl / 2; interval > minExpiryInterval {
+		return interval
+	}
+	return minExpiryInterval
+}
+
+// expireWaitersLoop evicts listeners older than n.listenerTTL until done is closed.
+func (n *notifier) expireWaitersLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(expiryInterval(n.listenerTTL))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			n.expireWaiters(now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// expireWaiters evicts listeners that started listening more than n.listenerTTL before now.
+func (n *notifier) expireWaiters(now time.Time) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	for id, since := range n.waiterSince {
+		if now.Sub(since) < n.listenerTTL {
+			continue
+		}
+		n.log.Printf("Evicting listeners for notification %d after %v without a closed signal", id, now.Sub(since))
+		s := &NotificationClosedSignal{ID: id, Reason: ReasonUnknown}
+		n.deliverEvent(NotificationEvent{ID: id, Closed: s})
+		n.deliverCloseWaiters(s)
+		delete(n.waiterSince, id)
+	}
+}
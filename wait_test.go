@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverClosedRemembersUnclaimedSignals(t *testing.T) {
+	n := newNotifier()
+
+	n.waitersMu.Lock()
+	ch := n.addCloseWaiter(1)
+	n.waitersMu.Unlock()
+
+	n.deliverClosed(&NotificationClosedSignal{ID: 1, Reason: ReasonExpired})
+	require.Equal(t, ReasonExpired, (<-ch).Reason)
+	require.NotContains(t, n.recentClosed, uint32(1))
+
+	for id := uint32(2); id < 2+recentClosedSize+1; id++ {
+		n.deliverClosed(&NotificationClosedSignal{ID: id, Reason: ReasonDismissedByUser})
+	}
+	require.Len(t, n.recentClosed, recentClosedSize)
+	require.Len(t, n.recentClosedOrder, recentClosedSize)
+	// the oldest is forgotten first
+	require.NotContains(t, n.recentClosed, uint32(2))
+	require.Contains(t, n.recentClosed, uint32(3))
+
+	n.forgetRecentClosed(3)
+	require.NotContains(t, n.recentClosed, uint32(3))
+	require.Len(t, n.recentClosedOrder, recentClosedSize-1)
+}
+
+func TestExpireWaiters(t *testing.T) {
+	n := newNotifier(WithListenerTTL(time.Minute), WithLogger(discardLogger{}))
+
+	n.waitersMu.Lock()
+	closed := n.addCloseWaiter(1)
+	event := make(chan NotificationEvent, 1)
+	n.eventWaiters[1] = append(n.eventWaiters[1], event)
+	n.trackWaiter(1)
+	fresh := n.addCloseWaiter(2)
+	n.waiterSince[1] = time.Now().Add(-2 * time.Minute)
+	n.waitersMu.Unlock()
+
+	n.expireWaiters(time.Now())
+	require.Equal(t, ReasonUnknown, (<-closed).Reason)
+	require.Equal(t, ReasonUnknown, (<-event).Closed.Reason)
+	require.NotContains(t, n.waiterSince, uint32(1))
+	require.Len(t, fresh, 0)
+	require.Contains(t, n.waiterSince, uint32(2))
+
+	n.removeCloseWaiter(2, fresh)
+	require.Empty(t, n.waiterSince)
+}
+
+func TestExpiryInterval(t *testing.T) {
+	require.Equal(t, 5*time.Second, expiryInterval(10*time.Second))
+	require.Equal(t, minExpiryInterval, expiryInterval(time.Millisecond))
+	// half of 1ns rounds down to 0, which time.NewTicker rejects
+	require.Equal(t, minExpiryInterval, expiryInterval(time.Nanosecond))
+}
+
+func TestForgetStaleClosed(t *testing.T) {
+	n := newNotifier()
+	n.deliverClosed(&NotificationClosedSignal{ID: 1, Reason: ReasonExpired})
+
+	// sending starts, then the server reuses ID 1 for the new notification
+	n.waitersMu.Lock()
+	seq := n.closedSeq
+	n.waitersMu.Unlock()
+	n.forgetStaleClosed(1, seq)
+	require.NotContains(t, n.recentClosed, uint32(1))
+	require.NotContains(t, n.recentClosedSeq, uint32(1))
+
+	// a signal received while sending belongs to the new notification, and is kept
+	n.waitersMu.Lock()
+	seq = n.closedSeq
+	n.waitersMu.Unlock()
+	n.deliverClosed(&NotificationClosedSignal{ID: 1, Reason: ReasonDismissedByUser})
+	n.forgetStaleClosed(1, seq)
+	require.Contains(t, n.recentClosed, uint32(1))
+}
@@ -0,0 +1,75 @@
+package notify
+
+// MaxWireSizeBytes is the estimated message size above which Validate warns.
+// The bus limits messages to 128 MB by default, but many systems configure 32 MB,
+// and large messages are slow to marshal and for the server to render.
+const MaxWireSizeBytes = 1 << 22
+
+// wireOverhead approximates the fixed size of the message header and the fixed size arguments of Notify.
+const wireOverhead = 128
+
+// wireOverheadPerValue approximates the padding, length and signature bytes around each string, action and hint.
+const wireOverheadPerValue = 16
+
+// EstimateWireSize approximates the size in bytes of the dbus message sending n.
+//
+// The estimate is the sum of the lengths of all strings, the length of the data of byte slice and
+// image hints, e.g. 4 bytes per pixel for HintImageDataRGBA, and a constant overhead per value.
+// Padding and other details of the wire format are not accounted for exactly,
+// so the estimate is meant for catching messages that are far too large, not for exact limits.
+func EstimateWireSize(n Notification) int {
+	size := wireOverhead
+	for _, s := range []string{n.AppName, n.AppIcon, n.Summary, n.Body} {
+		size += len(s) + wireOverheadPerValue
+	}
+	for _, a := range n.Actions {
+		size += len(a.Key) + len(a.Label) + 2*wireOverheadPerValue
+	}
+	for key, v := range n.Hints {
+		size += len(key) + wireOverheadPerValue
+		switch value := v.Value().(type) {
+		case ImageData:
+			size += len(value.Data) + wireOverheadPerValue
+		case []byte:
+			size += len(value)
+		case string:
+			size += len(value)
+		default:
+			size += 8
+		}
+	}
+	return size
+}
+
+// WithMaxNotificationSize makes SendNotification refuse notifications larger than maxBytes according
+// to EstimateWireSize, returning a *NotificationTooLargeError without sending them.
+// Use TrimToSize to make notifications fit.
+func WithMaxNotificationSize(maxBytes int) option {
+	return func(n *notifier) {
+		n.maxNotificationSize = maxBytes
+	}
+}
+
+// soundHintIDs are the hints carrying a sound.
+var soundHintIDs = []string{"sound-name", "sound-file", "sound-data"}
+
+// TrimToSize shrinks n to fit within maxBytes according to EstimateWireSize, by removing parts until it fits:
+// first the image hints, then the sound hints, and finally by truncating Body.
+// The result may still not fit if the other fields alone exceed maxBytes. n itself is not modified.
+func TrimToSize(n Notification, maxBytes int) Notification {
+	if EstimateWireSize(n) <= maxBytes {
+		return n
+	}
+	trimmed := n.Clone()
+	for _, ids := range [][]string{imageHintIDs, soundHintIDs} {
+		for _, id := range ids {
+			delete(trimmed.Hints, id)
+		}
+		if EstimateWireSize(trimmed) <= maxBytes {
+			return trimmed
+		}
+	}
+	excess := EstimateWireSize(trimmed) - maxBytes
+	trimmed.Body = truncateString(trimmed.Body, len(trimmed.Body)-excess, "…")
+	return trimmed
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateWireSize(t *testing.T) {
+	n := Notification{Summary: "summary", Body: "body"}
+	small := EstimateWireSize(n)
+	require.Greater(t, small, len("summary")+len("body"))
+	require.NoError(t, n.Validate())
+
+	n.AddHint(HintImageDataRGBA(image.NewRGBA(image.Rect(0, 0, 1920, 1080))))
+	size := EstimateWireSize(n)
+	require.GreaterOrEqual(t, size, 1920*1080*4)
+
+	var warning *ValidationWarning
+	require.True(t, errors.As(n.Validate(), &warning))
+	require.Equal(t, "Hints", warning.Field)
+
+	n.Hints = nil
+	n.AddHint(HintImageDataRGBA(image.NewRGBA(image.Rect(0, 0, 64, 64))))
+	require.NoError(t, n.Validate())
+}
+
+func TestTrimToSize(t *testing.T) {
+	n := Notification{Summary: "summary", Body: strings.Repeat("body ", 200)}
+	n.AddHint(HintImageDataRGBA(image.NewRGBA(image.Rect(0, 0, 64, 64))))
+	n.AddHint(HintSoundWithName("bell"))
+	n.SetUrgency(UrgencyCritical)
+
+	require.Equal(t, n, TrimToSize(n, EstimateWireSize(n)))
+
+	withoutImage := TrimToSize(n, EstimateWireSize(n)-1)
+	require.NotContains(t, withoutImage.Hints, "image-data")
+	require.Contains(t, withoutImage.Hints, "sound-name")
+	require.Equal(t, n.Body, withoutImage.Body)
+	// the original is not modified
+	require.Contains(t, n.Hints, "image-data")
+
+	small := TrimToSize(n, 512)
+	require.LessOrEqual(t, EstimateWireSize(small), 512)
+	require.NotContains(t, small.Hints, "sound-name")
+	require.Contains(t, small.Hints, "urgency")
+	require.True(t, strings.HasSuffix(small.Body, "…"))
+	require.True(t, strings.HasPrefix(n.Body, strings.TrimSuffix(small.Body, "…")))
+}
@@ -0,0 +1,217 @@
+//go:build yaml
+// +build yaml
+
+package notify
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlNotification is the YAML representation of a Notification.
+type yamlNotification struct {
+	AppName       string              `yaml:"appName,omitempty"`
+	ReplacesID    uint32              `yaml:"replacesId,omitempty"`
+	AppIcon       string              `yaml:"appIcon,omitempty"`
+	Summary       string              `yaml:"summary,omitempty"`
+	Body          string              `yaml:"body,omitempty"`
+	Actions       []Action            `yaml:"actions,omitempty"`
+	Hints         map[string]yamlHint `yaml:"hints,omitempty"`
+	Urgency       *Urgency            `yaml:"urgency,omitempty"`
+	ExpireTimeout time.Duration       `yaml:"expireTimeout,omitempty"`
+}
+
+// yamlHint annotates the value of a hint with its DBus type signature, e.g.:
+//
+//	urgency:
+//	  type: "y"
+//	  value: 2
+type yamlHint struct {
+	Type  string      `yaml:"type"`
+	Value interface{} `yaml:"value"`
+}
+
+var _ yaml.Marshaler = Notification{}
+var _ yaml.IsZeroer = Notification{}
+
+// MarshalYAML implements yaml.Marshaler.
+// Only hints of basic DBus types (strings, booleans and numbers) can be encoded.
+func (n Notification) MarshalYAML() (interface{}, error) {
+	out := yamlNotification{
+		AppName:       n.AppName,
+		ReplacesID:    n.ReplacesID,
+		AppIcon:       n.AppIcon,
+		Summary:       n.Summary,
+		Body:          n.Body,
+		Actions:       n.Actions,
+		Urgency:       n.Urgency,
+		ExpireTimeout: n.ExpireTimeout,
+	}
+	if len(n.Hints) > 0 {
+		out.Hints = make(map[string]yamlHint, len(n.Hints))
+	}
+	for key, variant := range n.Hints {
+		signature := variant.Signature().String()
+		if _, err := hintValueFromYAML(signature, variant.Value()); err != nil {
+			return nil, fmt.Errorf("error encoding hint %v: %w", key, err)
+		}
+		out.Hints[key] = yamlHint{Type: signature, Value: variant.Value()}
+	}
+	return out, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (n *Notification) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var in yamlNotification
+	if err := unmarshal(&in); err != nil {
+		return err
+	}
+	*n = Notification{
+		AppName:       in.AppName,
+		ReplacesID:    in.ReplacesID,
+		AppIcon:       in.AppIcon,
+		Summary:       in.Summary,
+		Body:          in.Body,
+		Actions:       in.Actions,
+		Urgency:       in.Urgency,
+		ExpireTimeout: in.ExpireTimeout,
+	}
+	for key, hint := range in.Hints {
+		value, err := hintValueFromYAML(hint.Type, hint.Value)
+		if err != nil {
+			return fmt.Errorf("error decoding hint %v: %w", key, err)
+		}
+		n.AddHint(Hint{ID: key, Variant: dbus.MakeVariant(value)})
+	}
+	return nil
+}
+
+// IsZero implements yaml.IsZeroer, so an unset Notification is left out with omitempty.
+func (n Notification) IsZero() bool {
+	return n.AppName == "" &&
+		n.ReplacesID == 0 &&
+		n.AppIcon == "" &&
+		n.Summary == "" &&
+		n.Body == "" &&
+		len(n.Actions) == 0 &&
+		len(n.Hints) == 0 &&
+		n.Urgency == nil &&
+		n.ExpireTimeout == 0
+}
+
+// hintValueFromYAML converts a decoded YAML value to the Go type matching the DBus signature.
+func hintValueFromYAML(signature string, value interface{}) (interface{}, error) {
+	switch signature {
+	case "s":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case "b":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case "d":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+	case "y", "n", "q", "i", "u", "x", "t":
+		abs, neg, ok := yamlInteger(value)
+		if !ok {
+			break
+		}
+		switch signature {
+		case "y":
+			if u, ok := yamlUnsigned(abs, neg, math.MaxUint8); ok {
+				return byte(u), nil
+			}
+		case "n":
+			if i, ok := yamlSigned(abs, neg, math.MaxInt16); ok {
+				return int16(i), nil
+			}
+		case "q":
+			if u, ok := yamlUnsigned(abs, neg, math.MaxUint16); ok {
+				return uint16(u), nil
+			}
+		case "i":
+			if i, ok := yamlSigned(abs, neg, math.MaxInt32); ok {
+				return int32(i), nil
+			}
+		case "u":
+			if u, ok := yamlUnsigned(abs, neg, math.MaxUint32); ok {
+				return uint32(u), nil
+			}
+		case "x":
+			if i, ok := yamlSigned(abs, neg, math.MaxInt64); ok {
+				return i, nil
+			}
+		case "t":
+			if u, ok := yamlUnsigned(abs, neg, math.MaxUint64); ok {
+				return u, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported hint type %q", signature)
+	}
+	return nil, fmt.Errorf("value %v is not of type %q", value, signature)
+}
+
+// yamlInteger returns the absolute value and sign of an integer decoded from YAML.
+func yamlInteger(value interface{}) (abs uint64, neg bool, ok bool) {
+	switch v := value.(type) {
+	case int:
+		return yamlInt64(int64(v))
+	case int64:
+		return yamlInt64(v)
+	case int16:
+		return yamlInt64(int64(v))
+	case int32:
+		return yamlInt64(int64(v))
+	case uint64:
+		return v, false, true
+	case byte:
+		return uint64(v), false, true
+	case uint16:
+		return uint64(v), false, true
+	case uint32:
+		return uint64(v), false, true
+	default:
+		return 0, false, false
+	}
+}
+
+func yamlInt64(v int64) (abs uint64, neg bool, ok bool) {
+	if v < 0 {
+		// -(v+1) does not overflow for math.MinInt64
+		return uint64(-(v + 1)) + 1, true, true
+	}
+	return uint64(v), false, true
+}
+
+// yamlSigned returns the integer if it is in the range [-max-1, max].
+func yamlSigned(abs uint64, neg bool, max uint64) (int64, bool) {
+	if neg {
+		if abs > max+1 {
+			return 0, false
+		}
+		return -int64(abs-1) - 1, true
+	}
+	if abs > max {
+		return 0, false
+	}
+	return int64(abs), true
+}
+
+// yamlUnsigned returns the integer if it is in the range [0, max].
+func yamlUnsigned(abs uint64, neg bool, max uint64) (uint64, bool) {
+	if neg || abs > max {
+		return 0, false
+	}
+	return abs, true
+}
@@ -0,0 +1,106 @@
+//go:build yaml
+// +build yaml
+
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNotificationYAMLRoundTrip(t *testing.T) {
+	n := Notification{
+		AppName:       "app",
+		AppIcon:       "mail-unread",
+		Summary:       "summary",
+		Body:          "body",
+		Actions:       []Action{{Key: "open", Label: "Open"}},
+		ExpireTimeout: 5 * time.Second,
+	}
+	n.SetUrgency(UrgencyCritical)
+	n.AddHint(HintSoundWithName("bell"))
+	n.AddHint(HintImageFilePath("/tmp/image.png"))
+	n.AddHint(Hint{ID: "resident", Variant: dbus.MakeVariant(true)})
+	n.AddHint(Hint{ID: "x", Variant: dbus.MakeVariant(int32(-12))})
+	n.AddHint(Hint{ID: "value", Variant: dbus.MakeVariant(uint32(50))})
+
+	out, err := yaml.Marshal(n)
+	require.NoError(t, err)
+
+	var decoded Notification
+	require.NoError(t, yaml.Unmarshal(out, &decoded))
+	require.Equal(t, n, decoded)
+}
+
+func TestNotificationYAMLDecode(t *testing.T) {
+	in := `
+appName: monitor
+summary: Disk full
+expireTimeout: 10s
+hints:
+  urgency:
+    type: "y"
+    value: 2
+`
+	var n Notification
+	require.NoError(t, yaml.Unmarshal([]byte(in), &n))
+	require.Equal(t, "monitor", n.AppName)
+	require.Equal(t, 10*time.Second, n.ExpireTimeout)
+	require.Equal(t, byte(UrgencyCritical), n.Hints["urgency"].Value())
+
+	require.Error(t, yaml.Unmarshal([]byte("hints: {urgency: {type: y, value: high}}"), &n))
+}
+
+func TestNotificationYAMLIntegerRange(t *testing.T) {
+	valid := map[string]interface{}{
+		"{type: y, value: 255}":                  byte(255),
+		"{type: n, value: -32768}":               int16(-32768),
+		"{type: q, value: 65535}":                uint16(65535),
+		"{type: i, value: -2147483648}":          int32(-2147483648),
+		"{type: u, value: 4294967295}":           uint32(4294967295),
+		"{type: x, value: -9223372036854775808}": int64(-9223372036854775808),
+		"{type: t, value: 18446744073709551615}": uint64(18446744073709551615),
+	}
+	for hint, want := range valid {
+		var n Notification
+		require.NoError(t, yaml.Unmarshal([]byte("hints: {h: "+hint+"}"), &n), hint)
+		require.Equal(t, want, n.Hints["h"].Value(), hint)
+	}
+
+	invalid := []string{
+		"{type: y, value: 300}",
+		"{type: y, value: -1}",
+		"{type: n, value: 32768}",
+		"{type: q, value: -1}",
+		"{type: i, value: 2147483648}",
+		"{type: u, value: -1}",
+		"{type: x, value: 9223372036854775808}",
+		"{type: t, value: -1}",
+	}
+	for _, hint := range invalid {
+		var n Notification
+		err := yaml.Unmarshal([]byte("hints: {h: "+hint+"}"), &n)
+		require.Error(t, err, hint)
+		require.Contains(t, err.Error(), "is not of type", hint)
+	}
+}
+
+func TestNotificationYAMLUnsupportedHint(t *testing.T) {
+	n := Notification{}
+	n.AddHint(Hint{ID: "image-data", Variant: ImageData{}.ToVariant()})
+	_, err := yaml.Marshal(n)
+	require.Error(t, err)
+}
+
+func TestNotificationYAMLIsZero(t *testing.T) {
+	type config struct {
+		Notification Notification `yaml:"notification,omitempty"`
+	}
+	out, err := yaml.Marshal(config{})
+	require.NoError(t, err)
+	require.Equal(t, "{}\n", string(out))
+}